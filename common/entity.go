@@ -17,6 +17,8 @@ type Entity struct {
 
 func (e Entity) GetID() uint { return e.ID } //nolint:gocritic // value receiver needed for interface satisfaction
 
+func (e Entity) GetExternalID() string { return e.ExternalID } //nolint:gocritic // value receiver needed for interface satisfaction
+
 func (e *Entity) BeforeCreate(tx *gorm.DB) (err error) {
 	if e.ExternalID == "" {
 		e.ExternalID = uuid.New().String()
@@ -12,6 +12,10 @@ type Log struct {
 	UserID    string         `json:"user_id,omitempty"`
 	Action    string         `json:"action"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
+	// PrevHash and Hash are populated by ChainProvider and must be persisted
+	// as-is for Verify to succeed.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 // NewHTTPLog creates a Log pre-populated with HTTP-specific metadata.
@@ -2,11 +2,13 @@ package audit
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/aawadallak/go-core-kit/core/logger"
+	"github.com/aawadallak/go-core-kit/core/redact"
 )
 
 func TestMain(m *testing.M) {
@@ -127,3 +129,154 @@ func TestOrchestratorDispatchAndFlush_Interval(t *testing.T) {
 		t.Errorf("expected Action %q, got %q", "interval-action", got[0].Action)
 	}
 }
+
+func TestOrchestratorDispatch_RedactsBeforeProvider(t *testing.T) {
+	spy := &spyProvider{}
+	ctx := t.Context()
+
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(1),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(spy),
+		WithRedactor(redact.New(redact.WithDefaultRules())),
+	)
+
+	err := orch.Dispatch(ctx, Log{
+		Action:   "user.update",
+		Metadata: map[string]any{"email": "jane@example.com", "password": "hunter2", "plan": "pro"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	got := spy.logs()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 flushed log, got %d", len(got))
+	}
+	metadata := got[0].Metadata
+	if metadata["email"] != redact.Masked || metadata["password"] != redact.Masked {
+		t.Errorf("expected sensitive metadata redacted, got %v", metadata)
+	}
+	if metadata["plan"] != "pro" {
+		t.Errorf("expected plan untouched, got %v", metadata["plan"])
+	}
+}
+
+func TestOrchestratorClose_DrainsAndFlushes(t *testing.T) {
+	spy := &spyProvider{}
+	orch := NewOrchestrator(t.Context(),
+		WithBatchSize(100),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(spy),
+	)
+
+	ctx := context.Background()
+	for _, action := range []string{"action-1", "action-2", "action-3"} {
+		if err := orch.Dispatch(ctx, Log{Action: action}); err != nil {
+			t.Fatalf("unexpected dispatch error: %v", err)
+		}
+	}
+
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := orch.Close(closeCtx); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	if got := spy.logs(); len(got) != 3 {
+		t.Fatalf("expected 3 flushed logs once Close returns, got %d", len(got))
+	}
+
+	if err := orch.Dispatch(ctx, Log{Action: "late"}); !errors.Is(err, ErrOrchestratorClosed) {
+		t.Fatalf("expected ErrOrchestratorClosed, got %v", err)
+	}
+	if err := orch.Close(ctx); err != nil {
+		t.Fatalf("expected repeated close to succeed, got %v", err)
+	}
+}
+
+// blockingProvider blocks every flush until its context is done.
+type blockingProvider struct{}
+
+func (blockingProvider) Close(_ context.Context) error { return nil }
+
+func (blockingProvider) Flush(ctx context.Context, _ ...Log) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestOrchestratorClose_RespectsDeadline(t *testing.T) {
+	orch := NewOrchestrator(t.Context(),
+		WithBatchSize(100),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(blockingProvider{}),
+	)
+
+	if err := orch.Dispatch(context.Background(), Log{Action: "action-1"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := orch.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("close did not honour the deadline, took %v", elapsed)
+	}
+}
+
+func TestOrchestratorClose_PendingDispatchDoesNotBlock(t *testing.T) {
+	orch := NewOrchestrator(t.Context(),
+		WithBatchSize(1),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(blockingProvider{}),
+	)
+
+	// The first entry fills the batch and parks the loop inside Flush.
+	if err := orch.Dispatch(context.Background(), Log{Action: "action-1"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+
+	pending := make(chan error, 1)
+	go func() {
+		pending <- orch.Dispatch(context.Background(), Log{Action: "action-2"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := orch.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("close did not honour the deadline, took %v", elapsed)
+	}
+
+	select {
+	case err := <-pending:
+		if !errors.Is(err, ErrOrchestratorClosed) {
+			t.Fatalf("expected ErrOrchestratorClosed for pending dispatch, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending dispatch still blocked after close")
+	}
+}
+
+func TestOrchestratorDispatch_AfterContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	orch := NewOrchestrator(ctx, WithProvider(&spyProvider{}))
+	cancel()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := orch.Dispatch(context.Background(), Log{Action: "late"}); !errors.Is(err, ErrOrchestratorClosed) {
+		t.Fatalf("expected ErrOrchestratorClosed, got %v", err)
+	}
+}
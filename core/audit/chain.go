@@ -0,0 +1,250 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrChainBroken is matched by the *ChainError returned from Verify.
+var ErrChainBroken = errors.New("audit chain is broken")
+
+// ChainError describes the first broken link found by Verify.
+type ChainError struct {
+	// Index is the zero-based position of the offending entry in the iterated range.
+	Index  int
+	Entry  Log
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at index %d (action=%s hash=%s): %s",
+		e.Index, e.Entry.Action, e.Entry.Hash, e.Reason)
+}
+
+func (e *ChainError) Is(target error) bool {
+	return target == ErrChainBroken
+}
+
+// Iterator walks a stored range of audit entries in insertion order.
+// Next returns false once the range is exhausted.
+type Iterator interface {
+	Next(ctx context.Context) (Log, bool, error)
+}
+
+// ChainProvider is a Provider decorator that links every entry to its
+// predecessor with a SHA-256 hash before delegating to the inner Provider.
+type ChainProvider struct {
+	inner Provider
+	mu    sync.Mutex
+	head  string
+}
+
+var _ Provider = (*ChainProvider)(nil)
+
+type ChainOption func(*ChainProvider)
+
+// WithChainHead seeds the chain with the hash of the last persisted entry so a
+// restarted process continues the existing chain instead of starting a new one.
+func WithChainHead(hash string) ChainOption {
+	return func(c *ChainProvider) {
+		c.head = hash
+	}
+}
+
+func NewChainProvider(inner Provider, opts ...ChainOption) *ChainProvider {
+	c := &ChainProvider{inner: inner}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Head returns the hash of the last entry successfully flushed.
+func (c *ChainProvider) Head() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head
+}
+
+// Flush implements Provider. The head only advances when the inner Provider
+// accepts the batch, so a retried batch is re-linked to the same predecessor.
+func (c *ChainProvider) Flush(ctx context.Context, entries ...Log) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	chained := make([]Log, len(entries))
+	prev := c.head
+	for i := range entries {
+		entry := entries[i]
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = now
+		}
+		entry.Timestamp = canonicalTime(entry.Timestamp)
+		entry.PrevHash = prev
+
+		hash, err := ComputeHash(prev, &entry)
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+
+		chained[i] = entry
+		prev = hash
+	}
+
+	if err := c.inner.Flush(ctx, chained...); err != nil {
+		return err
+	}
+
+	c.head = prev
+	return nil
+}
+
+// Close implements Provider.
+func (c *ChainProvider) Close(ctx context.Context) error {
+	return c.inner.Close(ctx)
+}
+
+// ComputeHash returns hex(sha256(prevHash || canonical JSON of the entry)).
+// PrevHash and Hash of the entry itself are excluded from the canonical form.
+func ComputeHash(prevHash string, l *Log) (string, error) {
+	canonical, err := canonicalJSON(l)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify walks the iterator and returns a *ChainError for the first entry whose
+// hash does not match its content or whose PrevHash does not match the hash of
+// the entry before it. The first entry's PrevHash anchors the range.
+func Verify(ctx context.Context, it Iterator) error {
+	var prev string
+	for i := 0; ; i++ {
+		entry, ok, err := it.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		if i > 0 && entry.PrevHash != prev {
+			return &ChainError{Index: i, Entry: entry, Reason: "prev_hash does not match previous entry"}
+		}
+
+		want, err := ComputeHash(entry.PrevHash, &entry)
+		if err != nil {
+			return err
+		}
+		if entry.Hash != want {
+			return &ChainError{Index: i, Entry: entry, Reason: "hash does not match entry content"}
+		}
+
+		prev = entry.Hash
+	}
+}
+
+// canonicalLog fixes field order and timestamp layout; encoding/json already
+// sorts map keys, which makes nested metadata deterministic.
+type canonicalLog struct {
+	Timestamp string `json:"timestamp"`
+	TraceID   string `json:"trace_id"`
+	UserID    string `json:"user_id"`
+	Action    string `json:"action"`
+	Metadata  any    `json:"metadata,omitempty"`
+}
+
+func canonicalJSON(l *Log) ([]byte, error) {
+	metadata, err := canonicalMetadata(l.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(canonicalLog{
+		Timestamp: canonicalTime(l.Timestamp).Format(time.RFC3339Nano),
+		TraceID:   l.TraceID,
+		UserID:    l.UserID,
+		Action:    l.Action,
+		Metadata:  metadata,
+	})
+}
+
+// canonicalMetadata rewrites every number in the metadata in one form, so an
+// int64 flushed as-is and the json.Number, or float64, read back from storage
+// hash to the same value whenever they are equal.
+func canonicalMetadata(m map[string]any) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("audit: marshal metadata: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("audit: decode metadata: %w", err)
+	}
+	return canonicalNumbers(v), nil
+}
+
+func canonicalNumbers(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			t[k] = canonicalNumbers(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = canonicalNumbers(e)
+		}
+	case json.Number:
+		return canonicalNumber(t)
+	}
+	return v
+}
+
+// canonicalNumber keeps integer literals, which are exact, and writes other
+// numbers as the float64 they parse to: integral values in plain decimal,
+// the rest in their shortest form.
+func canonicalNumber(n json.Number) json.Number {
+	if !strings.ContainsAny(string(n), ".eE") {
+		return n
+	}
+
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return n
+	}
+	if f == math.Trunc(f) {
+		return json.Number(new(big.Float).SetFloat64(f).Text('f', 0))
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// canonicalTime drops precision most databases cannot store so that an entry
+// read back from storage hashes to the same value.
+func canonicalTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
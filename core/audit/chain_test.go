@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type sliceIterator struct {
+	entries []Log
+	pos     int
+}
+
+func (s *sliceIterator) Next(_ context.Context) (Log, bool, error) {
+	if s.pos >= len(s.entries) {
+		return Log{}, false, nil
+	}
+	l := s.entries[s.pos]
+	s.pos++
+	return l, true, nil
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Close(_ context.Context) error { return nil }
+
+func (failingProvider) Flush(_ context.Context, _ ...Log) error { return errors.New("boom") }
+
+func chainedEntries(t *testing.T) []Log {
+	t.Helper()
+	spy := &spyProvider{}
+	chain := NewChainProvider(spy)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := chain.Flush(ctx,
+		Log{Timestamp: base, UserID: "u-1", Action: "login", Metadata: map[string]any{"ip": "10.0.0.1"}},
+		Log{Timestamp: base.Add(time.Second), UserID: "u-1", Action: "update"},
+	); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+	if err := chain.Flush(ctx, Log{Timestamp: base.Add(2 * time.Second), UserID: "u-2", Action: "delete"}); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	entries := spy.logs()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if chain.Head() != entries[2].Hash {
+		t.Fatalf("expected head %q, got %q", entries[2].Hash, chain.Head())
+	}
+	return entries
+}
+
+func TestChainProvider_LinksEntries(t *testing.T) {
+	entries := chainedEntries(t)
+
+	if entries[0].PrevHash != "" {
+		t.Errorf("expected empty prev hash for first entry, got %q", entries[0].PrevHash)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].PrevHash != entries[i-1].Hash {
+			t.Errorf("entry %d not linked to its predecessor", i)
+		}
+	}
+
+	if err := Verify(context.Background(), &sliceIterator{entries: entries}); err != nil {
+		t.Fatalf("expected valid chain, got %v", err)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func([]Log) []Log
+		index  int
+	}{
+		{
+			name: "edited metadata",
+			mutate: func(entries []Log) []Log {
+				entries[0].Metadata = map[string]any{"ip": "10.0.0.2"}
+				return entries
+			},
+			index: 0,
+		},
+		{
+			name: "edited action",
+			mutate: func(entries []Log) []Log {
+				entries[2].Action = "noop"
+				return entries
+			},
+			index: 2,
+		},
+		{
+			name: "deleted entry",
+			mutate: func(entries []Log) []Log {
+				return append(entries[:1], entries[2:]...)
+			},
+			index: 1,
+		},
+		{
+			name: "recomputed hash",
+			mutate: func(entries []Log) []Log {
+				entries[1].UserID = "u-9"
+				entries[1].Hash, _ = ComputeHash(entries[1].PrevHash, &entries[1])
+				return entries
+			},
+			index: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := tt.mutate(chainedEntries(t))
+
+			err := Verify(context.Background(), &sliceIterator{entries: entries})
+			if !errors.Is(err, ErrChainBroken) {
+				t.Fatalf("expected ErrChainBroken, got %v", err)
+			}
+
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				t.Fatalf("expected *ChainError, got %T", err)
+			}
+			if chainErr.Index != tt.index {
+				t.Fatalf("expected break at index %d, got %d", tt.index, chainErr.Index)
+			}
+		})
+	}
+}
+
+func TestChainProvider_HeadUnchangedOnFailure(t *testing.T) {
+	chain := NewChainProvider(failingProvider{}, WithChainHead("seed"))
+
+	if err := chain.Flush(context.Background(), Log{Action: "x"}); err == nil {
+		t.Fatal("expected flush error")
+	}
+	if chain.Head() != "seed" {
+		t.Fatalf("expected head to stay %q, got %q", "seed", chain.Head())
+	}
+}
+
+func TestComputeHash_NormalizesNumbers(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	flushed := Log{Timestamp: base, Action: "transfer", Metadata: map[string]any{
+		"amount": int64(9007199254740993),
+		"rate":   1.5,
+		"limits": []any{map[string]any{"max": float64(100)}},
+	}}
+	stored := Log{Timestamp: base, Action: "transfer", Metadata: map[string]any{
+		"amount": json.Number("9007199254740993"),
+		"rate":   json.Number("1.50"),
+		"limits": []any{map[string]any{"max": json.Number("1e2")}},
+	}}
+
+	want, err := ComputeHash("", &flushed)
+	if err != nil {
+		t.Fatalf("unexpected hash error: %v", err)
+	}
+	got, err := ComputeHash("", &stored)
+	if err != nil {
+		t.Fatalf("unexpected hash error: %v", err)
+	}
+	if got != want {
+		t.Fatal("expected equal numbers to hash the same regardless of representation")
+	}
+
+	stored.Metadata["amount"] = json.Number("9007199254740992")
+	if got, _ := ComputeHash("", &stored); got == want {
+		t.Fatal("expected a different large integer to change the hash")
+	}
+}
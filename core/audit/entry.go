@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+)
+
+// Operation is the kind of change an Entry records.
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationRead   Operation = "read"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Entry is a typed audit record about one resource. Before and After are the
+// resource states around the operation; either may be nil, e.g. Before on
+// create and After on delete.
+type Entry[T any] struct {
+	Actor        string
+	ResourceType string
+	ResourceID   string
+	Operation    Operation
+	Before       *T
+	After        *T
+	// IgnoreFields lists JSON field paths left out of the diff, such as "updated_at".
+	IgnoreFields []string
+}
+
+// Log converts the entry into a Log with Action "<resource type>.<operation>"
+// and metadata holding the resource, the operation and the field-level
+// changes as {"path": {"before": ..., "after": ...}}.
+func (e *Entry[T]) Log() (Log, error) {
+	metadata := map[string]any{
+		"resource_type": e.ResourceType,
+		"resource_id":   e.ResourceID,
+		"operation":     string(e.Operation),
+	}
+
+	if e.Operation != OperationRead {
+		changes, err := Diff(e.Before, e.After, e.IgnoreFields...)
+		if err != nil {
+			return Log{}, err
+		}
+		if len(changes) > 0 {
+			metadata["changes"] = changes
+		}
+	}
+
+	return Log{
+		Timestamp: time.Now(),
+		UserID:    e.Actor,
+		Action:    e.ResourceType + "." + string(e.Operation),
+		Metadata:  metadata,
+	}, nil
+}
+
+// Record converts the entry and dispatches it. A missing Actor is taken from
+// ActorFrom and the trace ID from common.RequestContext.
+func Record[T any](ctx context.Context, svc Service, e *Entry[T]) error {
+	if e.Actor == "" {
+		e.Actor = ActorFrom(ctx)
+	}
+
+	l, err := e.Log()
+	if err != nil {
+		return err
+	}
+	if rc := common.RequestContextFrom(ctx); rc != nil {
+		l.TraceID = rc.TraceID
+	}
+
+	return svc.Dispatch(ctx, l)
+}
+
+// Diff compares the JSON representations of before and after and returns the
+// changed leaf paths, nested objects joined with ".". Arrays are compared as
+// a whole. A nil side contributes no fields.
+func Diff(before, after any, ignore ...string) (map[string]any, error) {
+	a, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	b, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]any)
+	for path, old := range a {
+		if slices.Contains(ignore, path) {
+			continue
+		}
+		updated, ok := b[path]
+		if ok && reflect.DeepEqual(old, updated) {
+			continue
+		}
+		changes[path] = map[string]any{"before": old, "after": updated}
+	}
+	for path, updated := range b {
+		if _, ok := a[path]; ok || slices.Contains(ignore, path) {
+			continue
+		}
+		changes[path] = map[string]any{"before": nil, "after": updated}
+	}
+
+	return changes, nil
+}
+
+func flatten(v any) (map[string]any, error) {
+	out := make(map[string]any)
+	if v == nil {
+		return out, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return out, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit: marshal diff value: %w", err)
+	}
+
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("audit: unmarshal diff value: %w", err)
+	}
+
+	object, ok := decoded.(map[string]any)
+	if !ok {
+		out[""] = decoded
+		return out, nil
+	}
+	flattenInto(out, "", object)
+	return out, nil
+}
+
+func flattenInto(out map[string]any, prefix string, object map[string]any) {
+	for key, value := range object {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenInto(out, path, nested)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+type actorKey struct{}
+
+// WithActor stores the ID of the user or service performing the operation.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored by WithActor, or an empty string.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/common"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type account struct {
+	Name    string   `json:"name"`
+	Plan    string   `json:"plan"`
+	Tags    []string `json:"tags"`
+	Address address  `json:"address"`
+}
+
+func change(t *testing.T, changes map[string]any, path string) map[string]any {
+	t.Helper()
+	c, ok := changes[path].(map[string]any)
+	if !ok {
+		t.Fatalf("expected change for %q, got %v", path, changes)
+	}
+	return c
+}
+
+func TestDiff(t *testing.T) {
+	before := &account{Name: "acme", Plan: "free", Tags: []string{"a"}, Address: address{City: "Lisbon", Zip: "1000"}}
+	after := &account{Name: "acme", Plan: "pro", Tags: []string{"a", "b"}, Address: address{City: "Porto", Zip: "1000"}}
+
+	changes, err := Diff(before, after, "tags")
+	if err != nil {
+		t.Fatalf("unexpected diff error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if c := change(t, changes, "plan"); c["before"] != "free" || c["after"] != "pro" {
+		t.Errorf("unexpected plan change: %v", c)
+	}
+	if c := change(t, changes, "address.city"); c["before"] != "Lisbon" || c["after"] != "Porto" {
+		t.Errorf("unexpected nested change: %v", c)
+	}
+
+	var missing *account
+	created, err := Diff(missing, after)
+	if err != nil {
+		t.Fatalf("unexpected diff error: %v", err)
+	}
+	if c := change(t, created, "name"); c["before"] != nil || c["after"] != "acme" {
+		t.Errorf("unexpected create change: %v", c)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	spy := &chanService{logs: make(chan Log, 1)}
+	ctx := WithActor(context.Background(), "user-1")
+	ctx = common.WithRequestContext(ctx, &common.RequestContext{TraceID: "trace-1"})
+
+	err := Record(ctx, spy, &Entry[account]{
+		ResourceType: "account",
+		ResourceID:   "acc-1",
+		Operation:    OperationUpdate,
+		Before:       &account{Plan: "free"},
+		After:        &account{Plan: "pro"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected record error: %v", err)
+	}
+
+	l := <-spy.logs
+	if l.Action != "account.update" || l.UserID != "user-1" || l.TraceID != "trace-1" {
+		t.Errorf("unexpected log: %+v", l)
+	}
+	if l.Metadata["resource_id"] != "acc-1" || l.Metadata["operation"] != "update" {
+		t.Errorf("unexpected metadata: %v", l.Metadata)
+	}
+	changes, ok := l.Metadata["changes"].(map[string]any)
+	if !ok || len(changes) != 1 {
+		t.Fatalf("expected a single change, got %v", l.Metadata["changes"])
+	}
+}
+
+type chanService struct {
+	logs chan Log
+}
+
+func (c *chanService) Dispatch(_ context.Context, log Log) error {
+	c.logs <- log
+	return nil
+}
+
+func (c *chanService) Flush(_ context.Context) error { return nil }
+
+func (c *chanService) Close(_ context.Context) error { return nil }
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// FailurePolicy decides how MultiProvider reports partial failures.
+type FailurePolicy int
+
+const (
+	// AllMustSucceed fails the flush when any sink fails. A retried batch is
+	// sent again to every sink, so sinks should tolerate duplicates.
+	AllMustSucceed FailurePolicy = iota
+	// BestEffort only fails the flush when every sink that received entries
+	// failed; other failures are logged.
+	BestEffort
+)
+
+// Predicate selects the entries a sink receives.
+type Predicate func(l *Log) bool
+
+// ActionPrefix matches entries whose Action starts with prefix.
+func ActionPrefix(prefix string) Predicate {
+	return func(l *Log) bool {
+		return strings.HasPrefix(l.Action, prefix)
+	}
+}
+
+// Sink is a named destination of a MultiProvider. A nil Filter accepts every entry.
+type Sink struct {
+	Name     string
+	Provider Provider
+	Filter   Predicate
+}
+
+// MultiProvider fans every batch out to several sinks concurrently.
+type MultiProvider struct {
+	sinks  []Sink
+	policy FailurePolicy
+}
+
+var _ Provider = (*MultiProvider)(nil)
+
+func NewMultiProvider(policy FailurePolicy, sinks ...Sink) *MultiProvider {
+	return &MultiProvider{sinks: sinks, policy: policy}
+}
+
+// Flush implements Provider. Sinks whose filter matches none of the entries
+// are skipped. Errors name the sinks that failed.
+func (m *MultiProvider) Flush(ctx context.Context, entries ...Log) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []error
+		attempts int
+	)
+
+	for _, sink := range m.sinks {
+		batch := filterEntries(entries, sink.Filter)
+		if len(batch) == 0 {
+			continue
+		}
+
+		attempts++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sink.Provider.Flush(ctx, batch...); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("audit sink %q: %w", sink.Name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	err := errors.Join(errs...)
+	if m.policy == BestEffort && len(errs) < attempts {
+		logger.Of(ctx).ErrorS("Audit::MultiProvider::Flush", logger.WithValue("error", err))
+		return nil
+	}
+	return err
+}
+
+// Close implements Provider by closing every sink.
+func (m *MultiProvider) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.Provider.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("audit sink %q: %w", sink.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func filterEntries(entries []Log, filter Predicate) []Log {
+	if filter == nil {
+		return entries
+	}
+
+	matched := make([]Log, 0, len(entries))
+	for i := range entries {
+		if filter(&entries[i]) {
+			matched = append(matched, entries[i])
+		}
+	}
+	return matched
+}
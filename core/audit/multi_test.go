@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMultiProvider_FiltersPerSink(t *testing.T) {
+	all := &spyProvider{}
+	admin := &spyProvider{}
+
+	multi := NewMultiProvider(AllMustSucceed,
+		Sink{Name: "db", Provider: all},
+		Sink{Name: "broker", Provider: admin, Filter: ActionPrefix("admin.")},
+	)
+
+	err := multi.Flush(context.Background(),
+		Log{Action: "admin.user.create"},
+		Log{Action: "GET /items"},
+		Log{Action: "admin.role.grant"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if got := all.logs(); len(got) != 3 {
+		t.Errorf("expected 3 entries in unfiltered sink, got %d", len(got))
+	}
+	got := admin.logs()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries in filtered sink, got %d", len(got))
+	}
+	for _, l := range got {
+		if !strings.HasPrefix(l.Action, "admin.") {
+			t.Errorf("unexpected entry %q in filtered sink", l.Action)
+		}
+	}
+}
+
+func TestMultiProvider_FailurePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  FailurePolicy
+		sinks   []Sink
+		wantErr bool
+	}{
+		{
+			name:    "all must succeed fails on partial failure",
+			policy:  AllMustSucceed,
+			sinks:   []Sink{{Name: "db", Provider: &spyProvider{}}, {Name: "broker", Provider: failingProvider{}}},
+			wantErr: true,
+		},
+		{
+			name:   "best effort tolerates partial failure",
+			policy: BestEffort,
+			sinks:  []Sink{{Name: "db", Provider: &spyProvider{}}, {Name: "broker", Provider: failingProvider{}}},
+		},
+		{
+			name:    "best effort fails when every sink fails",
+			policy:  BestEffort,
+			sinks:   []Sink{{Name: "db", Provider: failingProvider{}}, {Name: "broker", Provider: failingProvider{}}},
+			wantErr: true,
+		},
+		{
+			name:   "best effort ignores skipped sinks",
+			policy: BestEffort,
+			sinks: []Sink{
+				{Name: "db", Provider: &spyProvider{}},
+				{Name: "broker", Provider: failingProvider{}, Filter: ActionPrefix("admin.")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewMultiProvider(tt.policy, tt.sinks...).Flush(context.Background(), Log{Action: "login"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMultiProvider_ErrorNamesFailedSinks(t *testing.T) {
+	multi := NewMultiProvider(AllMustSucceed,
+		Sink{Name: "db", Provider: &spyProvider{}},
+		Sink{Name: "broker", Provider: failingProvider{}},
+		Sink{Name: "archive", Provider: failingProvider{}},
+	)
+
+	err := multi.Flush(context.Background(), Log{Action: "login"})
+	if err == nil {
+		t.Fatal("expected flush error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `"broker"`) || !strings.Contains(msg, `"archive"`) || strings.Contains(msg, `"db"`) {
+		t.Fatalf("expected error to name only failed sinks, got %q", msg)
+	}
+}
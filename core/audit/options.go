@@ -1,6 +1,10 @@
 package audit
 
-import "time"
+import (
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/redact"
+)
 
 type Options func(*Orchestrator)
 
@@ -21,3 +25,39 @@ func WithProvider(provider Provider) Options {
 		o.provider = provider
 	}
 }
+
+// WithWAL spills every dispatched entry to segment files under dir before it
+// is batched. Entries left by a previous process are replayed on startup and
+// the files are truncated after each successful flush. maxBytes bounds the
+// total size on disk (0 means unbounded) and policy decides what happens when
+// the bound is reached. If dir cannot be opened the Orchestrator logs the
+// error and falls back to buffering in memory.
+func WithWAL(dir string, maxBytes int64, policy WALPolicy) Options {
+	return func(o *Orchestrator) {
+		o.wal = newWAL(dir, maxBytes, policy)
+	}
+}
+
+// WithRetryPolicy retries failed provider flushes according to policy. By
+// default a failed flush is not retried and the batch stays buffered.
+func WithRetryPolicy(policy RetryPolicy) Options {
+	return func(o *Orchestrator) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithDeadLetter sets a provider that receives batches that exhausted the
+// retry policy, so they are cleared from the buffer instead of retried forever.
+func WithDeadLetter(provider Provider) Options {
+	return func(o *Orchestrator) {
+		o.deadLetter = provider
+	}
+}
+
+// WithRedactor redacts the metadata of every entry, using its Action for
+// allowlists, before it is written to the WAL or reaches any Provider.
+func WithRedactor(r *redact.Redactor) Options {
+	return func(o *Orchestrator) {
+		o.redactor = r
+	}
+}
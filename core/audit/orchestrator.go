@@ -2,42 +2,81 @@ package audit
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/aawadallak/go-core-kit/core/logger"
+	"github.com/aawadallak/go-core-kit/core/redact"
 )
 
+// ErrOrchestratorClosed is returned by Dispatch once Close was called or the
+// context given to NewOrchestrator is done.
+var ErrOrchestratorClosed = errors.New("audit orchestrator is closed")
+
 type Orchestrator struct {
+	// Stream feeds the background loop. Prefer Dispatch: a bare send on
+	// Stream after Close blocks forever.
 	Stream        chan Log
 	once          sync.Once
+	closing       chan struct{}
+	closeCtx      context.Context
+	done          chan struct{}
 	batch         []Log
 	batchSize     int
 	batchInterval time.Duration
 	batchLock     sync.Mutex
+	sealed        bool
+	flushNow      chan struct{}
 	provider      Provider
+	wal           *wal
+	retryPolicy   RetryPolicy
+	deadLetter    Provider
+	stats         counters
+	redactor      *redact.Redactor
 }
 
-// Close implements audit.Provider.
+var _ Service = (*Orchestrator)(nil)
+
+// Close implements Service. It stops accepting entries and waits until the
+// background loop has drained the stream and run the final flush, or until
+// ctx is done. ctx also bounds the final flush and its retries.
 func (o *Orchestrator) Close(ctx context.Context) error {
 	o.once.Do(func() {
-		close(o.Stream)
+		o.closeCtx = ctx
+		close(o.closing)
 	})
 
-	if err := ctx.Err(); err != nil {
-		return err
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-
-	return nil
 }
 
-// Dispatch implements audit.Provider.
-func (o *Orchestrator) Dispatch(ctx context.Context, log *Log) error {
+// Dispatch implements Service. Under WALError the entry is written to the
+// WAL before Dispatch returns, so a full WAL is reported as ErrWALFull.
+func (o *Orchestrator) Dispatch(ctx context.Context, log Log) error {
+	select {
+	case <-o.closing:
+		return ErrOrchestratorClosed
+	default:
+	}
+
+	if o.wal != nil && o.wal.policy == WALError {
+		return o.dispatchSync(ctx, log)
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	default:
-		o.Stream <- *log
+	case <-o.closing:
+		return ErrOrchestratorClosed
+	case <-o.done:
+		return ErrOrchestratorClosed
+	case o.Stream <- log:
 	}
 
 	return nil
@@ -48,23 +87,136 @@ func (o *Orchestrator) Flush(ctx context.Context) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
+		o.batchLock.Lock()
+		defer o.batchLock.Unlock()
+
 		if len(o.batch) == 0 {
 			return nil
 		}
 
-		o.batchLock.Lock()
-		if err := o.provider.Flush(ctx, o.batch...); err != nil {
-			return err
+		if err := o.flushWithRetry(ctx, o.batch); err != nil {
+			if !o.flushDeadLetter(ctx, err) {
+				o.stats.failed.Add(1)
+				return err
+			}
 		}
 
 		o.batch = make([]Log, 0, o.batchSize)
-		o.batchLock.Unlock()
+
+		if o.wal != nil {
+			if err := o.wal.reset(); err != nil {
+				return fmt.Errorf("audit: truncate wal: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// flushDeadLetter hands the batch that exhausted retries to the dead-letter
+// provider and reports whether it was accepted.
+func (o *Orchestrator) flushDeadLetter(ctx context.Context, cause error) bool {
+	if o.deadLetter == nil {
+		return false
+	}
+
+	if err := o.deadLetter.Flush(ctx, o.batch...); err != nil {
+		o.stats.deadLetterErrors.Add(1)
+		logger.Of(ctx).ErrorS("Audit::Provider::DeadLetter",
+			logger.WithValue("error", err),
+			logger.WithValue("cause", cause))
+		return false
+	}
+
+	o.stats.deadLettered.Add(uint64(len(o.batch)))
+	logger.Of(ctx).WarnS("Audit::Provider::DeadLetter",
+		logger.WithValue("message", "batch dead-lettered after exhausting retries"),
+		logger.WithValue("entries", len(o.batch)),
+		logger.WithValue("cause", cause))
+	return true
+}
+
+// Stats returns a snapshot of the flush counters.
+func (o *Orchestrator) Stats() Stats {
+	return o.stats.snapshot()
+}
+
+// dispatchSync redacts and enqueues the entry on the caller's goroutine and
+// asks the loop to flush once the batch is full.
+func (o *Orchestrator) dispatchSync(ctx context.Context, log Log) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	o.redact(&log)
+	batchLen, err := o.enqueue(ctx, &log)
+	if err != nil {
+		return err
+	}
+
+	if batchLen >= o.batchSize {
+		select {
+		case o.flushNow <- struct{}{}:
+		default:
+		}
+	}
 	return nil
 }
 
+// enqueue writes the entry ahead to the WAL, when configured, and appends it
+// to the batch. It returns the batch length after the append.
+func (o *Orchestrator) enqueue(ctx context.Context, log *Log) (int, error) {
+	o.batchLock.Lock()
+	defer o.batchLock.Unlock()
+
+	if o.sealed {
+		return len(o.batch), ErrOrchestratorClosed
+	}
+
+	if o.wal != nil {
+		dropped, err := o.wal.append(log)
+		if dropped > 0 {
+			o.batch = o.batch[min(dropped, len(o.batch)):]
+			logger.Of(ctx).WarnS("Audit::Provider::WAL",
+				logger.WithValue("message", "wal full, dropped oldest entries"),
+				logger.WithValue("dropped", dropped))
+		}
+		if err != nil {
+			return len(o.batch), err
+		}
+	}
+
+	o.batch = append(o.batch, *log)
+	return len(o.batch), nil
+}
+
+// write enqueues the entry. Under WALBlock a full WAL keeps the loop, and
+// therefore Dispatch, waiting here while it retries flushing to free space.
+func (o *Orchestrator) write(ctx context.Context, log *Log) (int, error) {
+	for {
+		batchLen, err := o.enqueue(ctx, log)
+		if !errors.Is(err, ErrWALFull) || errors.Is(err, errEntryTooLarge) ||
+			o.wal.policy != WALBlock {
+			return batchLen, err
+		}
+
+		err = o.Flush(ctx)
+		if err == nil {
+			continue
+		}
+		logger.Of(ctx).ErrorS("Audit::Provider::Flush", logger.WithValue("error", err))
+
+		select {
+		case <-ctx.Done():
+			return batchLen, ctx.Err()
+		case <-time.After(o.batchInterval):
+		}
+	}
+}
+
 func (o *Orchestrator) start(ctx context.Context) {
+	defer close(o.done)
+
 	ticker := time.NewTicker(o.batchInterval)
 	defer ticker.Stop()
 
@@ -73,28 +225,25 @@ func (o *Orchestrator) start(ctx context.Context) {
 		case <-ctx.Done():
 			logger.Of(ctx).InfoS("Audit::Provider::dispatch", logger.
 				WithValue("message", "context done"))
+			o.closeWAL(ctx)
 			return
-		case log, ok := <-o.Stream:
-			if !ok {
-				if err := o.Flush(ctx); err != nil {
-					logger.Of(ctx).ErrorS("Audit::Provider::FinalFlush",
-						logger.WithValue("error", err))
-				}
-				return
+		case <-o.closing:
+			o.drain(ctx)
+			o.seal()
+			if err := o.Flush(o.closeCtx); err != nil {
+				logger.Of(ctx).ErrorS("Audit::Provider::FinalFlush",
+					logger.WithValue("error", err))
 			}
-
-			o.batchLock.Lock()
-			o.batch = append(o.batch, log)
-			batchLen := len(o.batch)
-			o.batchLock.Unlock()
-
-			if batchLen >= o.batchSize {
-				if err := o.Flush(ctx); err != nil {
-					logger.Of(ctx).ErrorS(
-						"Audit::Provider::Flush",
-						logger.WithValue("error", err),
-					)
-				}
+			o.closeWAL(ctx)
+			return
+		case log := <-o.Stream:
+			o.receive(ctx, log)
+		case <-o.flushNow:
+			if err := o.Flush(ctx); err != nil {
+				logger.Of(ctx).ErrorS(
+					"Audit::Provider::Flush",
+					logger.WithValue("error", err),
+				)
 			}
 		case <-ticker.C:
 			if err := o.Flush(ctx); err != nil {
@@ -107,9 +256,74 @@ func (o *Orchestrator) start(ctx context.Context) {
 	}
 }
 
+// receive redacts and enqueues one entry, flushing once the batch is full.
+func (o *Orchestrator) receive(ctx context.Context, log Log) {
+	o.redact(&log)
+
+	batchLen, err := o.write(ctx, &log)
+	if err != nil {
+		logger.Of(ctx).ErrorS("Audit::Provider::WAL",
+			logger.WithValue("error", err))
+		return
+	}
+
+	if batchLen >= o.batchSize {
+		if err := o.Flush(ctx); err != nil {
+			logger.Of(ctx).ErrorS(
+				"Audit::Provider::Flush",
+				logger.WithValue("error", err),
+			)
+		}
+	}
+}
+
+func (o *Orchestrator) redact(log *Log) {
+	if o.redactor != nil {
+		log.Metadata = o.redactor.Map(log.Action, log.Metadata)
+	}
+}
+
+// seal rejects every later enqueue, so entries dispatched synchronously
+// cannot land in the batch after the final flush.
+func (o *Orchestrator) seal() {
+	o.batchLock.Lock()
+	defer o.batchLock.Unlock()
+	o.sealed = true
+}
+
+// drain takes the entries whose senders won the race against Close, so every
+// Dispatch that returned nil is part of the final flush.
+func (o *Orchestrator) drain(ctx context.Context) {
+	for {
+		select {
+		case log := <-o.Stream:
+			o.receive(ctx, log)
+		default:
+			return
+		}
+	}
+}
+
+func (o *Orchestrator) closeWAL(ctx context.Context) {
+	if o.wal == nil {
+		return
+	}
+
+	o.batchLock.Lock()
+	defer o.batchLock.Unlock()
+
+	o.sealed = true
+	if err := o.wal.close(); err != nil {
+		logger.Of(ctx).ErrorS("Audit::Provider::WAL", logger.WithValue("error", err))
+	}
+}
+
 func NewOrchestrator(ctx context.Context, opts ...Options) *Orchestrator {
 	orchestrator := &Orchestrator{
 		Stream:        make(chan Log),
+		closing:       make(chan struct{}),
+		flushNow:      make(chan struct{}, 1),
+		done:          make(chan struct{}),
 		batchSize:     1,
 		batchInterval: 10 * time.Second,
 		provider:      NewStandardProvider(),
@@ -121,6 +335,17 @@ func NewOrchestrator(ctx context.Context, opts ...Options) *Orchestrator {
 
 	orchestrator.batch = make([]Log, 0, orchestrator.batchSize)
 
+	if orchestrator.wal != nil {
+		recovered, err := orchestrator.wal.open()
+		if err != nil {
+			logger.Of(ctx).ErrorS("Audit::Provider::WAL",
+				logger.WithValue("message", "failed to open wal, buffering in memory only"),
+				logger.WithValue("error", err))
+			orchestrator.wal = nil
+		}
+		orchestrator.batch = append(orchestrator.batch, recovered...)
+	}
+
 	go orchestrator.start(ctx)
 
 	return orchestrator
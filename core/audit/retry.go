@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how often the Orchestrator retries a failed
+// provider.Flush before the batch is handed to the dead-letter provider.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomises up to this fraction (0..1) of every backoff.
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries three times, backing off exponentially from
+// 100ms up to 5s with 20% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+func (p RetryPolicy) attempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// backoff returns the wait before retry number attempt (zero-based).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := max(p.Multiplier, 1)
+	value := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt))
+	if p.MaxBackoff > 0 && value > float64(p.MaxBackoff) {
+		value = float64(p.MaxBackoff)
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	value = value*(1-jitter) + rand.Float64()*value*jitter
+
+	return time.Duration(value)
+}
+
+// Stats is a snapshot of the Orchestrator flush counters.
+type Stats struct {
+	// Flushed counts entries accepted by the provider.
+	Flushed uint64
+	// Retries counts provider.Flush attempts beyond the first one.
+	Retries uint64
+	// Failed counts batches that exhausted retries and stayed buffered.
+	Failed uint64
+	// DeadLettered counts entries accepted by the dead-letter provider.
+	DeadLettered uint64
+	// DeadLetterErrors counts batches the dead-letter provider rejected.
+	DeadLetterErrors uint64
+}
+
+type counters struct {
+	flushed          atomic.Uint64
+	retries          atomic.Uint64
+	failed           atomic.Uint64
+	deadLettered     atomic.Uint64
+	deadLetterErrors atomic.Uint64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Flushed:          c.flushed.Load(),
+		Retries:          c.retries.Load(),
+		Failed:           c.failed.Load(),
+		DeadLettered:     c.deadLettered.Load(),
+		DeadLetterErrors: c.deadLetterErrors.Load(),
+	}
+}
+
+// flushWithRetry calls provider.Flush until it succeeds, the policy is
+// exhausted or ctx is done, and returns the last error.
+func (o *Orchestrator) flushWithRetry(ctx context.Context, entries []Log) error {
+	var err error
+	for attempt := range o.retryPolicy.attempts() {
+		if attempt > 0 {
+			o.stats.retries.Add(1)
+			if waitErr := sleepWithContext(ctx, o.retryPolicy.backoff(attempt-1)); waitErr != nil {
+				return err
+			}
+		}
+
+		if err = o.provider.Flush(ctx, entries...); err == nil {
+			o.stats.flushed.Add(uint64(len(entries)))
+			return nil
+		}
+	}
+	return err
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
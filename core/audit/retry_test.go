@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakySpyProvider fails the first failures calls to Flush, then records
+// flushed logs like spyProvider.
+type flakySpyProvider struct {
+	spyProvider
+	failures int
+	calls    int
+}
+
+func (f *flakySpyProvider) Flush(ctx context.Context, entries ...Log) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		return errors.New("temporary failure")
+	}
+	return f.spyProvider.Flush(ctx, entries...)
+}
+
+func testRetryPolicy(attempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    attempts,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+func TestOrchestratorFlush_RetriesUntilSuccess(t *testing.T) {
+	flaky := &flakySpyProvider{failures: 2}
+	ctx := t.Context()
+
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(1),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(flaky),
+		WithRetryPolicy(testRetryPolicy(3)),
+	)
+
+	if err := orch.Dispatch(ctx, Log{Action: "action-1"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if got := flaky.logs(); len(got) != 1 {
+		t.Fatalf("expected 1 flushed log, got %d", len(got))
+	}
+
+	stats := orch.Stats()
+	if stats.Retries != 2 || stats.Flushed != 1 || stats.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestOrchestratorFlush_DeadLettersExhaustedBatch(t *testing.T) {
+	flaky := &flakySpyProvider{failures: 100}
+	deadLetter := &spyProvider{}
+	ctx := t.Context()
+
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(2),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(flaky),
+		WithRetryPolicy(testRetryPolicy(3)),
+		WithDeadLetter(deadLetter),
+	)
+
+	for _, action := range []string{"action-1", "action-2"} {
+		if err := orch.Dispatch(ctx, Log{Action: action}); err != nil {
+			t.Fatalf("unexpected dispatch error: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if got := deadLetter.logs(); len(got) != 2 {
+		t.Fatalf("expected 2 dead-lettered logs, got %d", len(got))
+	}
+
+	stats := orch.Stats()
+	if stats.Retries != 2 || stats.DeadLettered != 2 || stats.Flushed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// The batch was cleared, so an explicit flush has nothing left to send.
+	if err := orch.Flush(ctx); err != nil {
+		t.Fatalf("expected empty batch after dead-letter, got %v", err)
+	}
+}
+
+func TestOrchestratorFlush_KeepsBatchAndReleasesLockOnFailure(t *testing.T) {
+	flaky := &flakySpyProvider{failures: 2}
+	ctx := t.Context()
+
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(100),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(flaky),
+	)
+
+	if err := orch.Dispatch(ctx, Log{Action: "action-1"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	for range 2 {
+		if err := orch.Flush(ctx); err == nil {
+			t.Fatal("expected flush error")
+		}
+	}
+	if err := orch.Flush(ctx); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if got := flaky.logs(); len(got) != 1 {
+		t.Fatalf("expected buffered log to be flushed, got %d", len(got))
+	}
+	if stats := orch.Stats(); stats.Failed != 2 || stats.Flushed != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: 0, base: 100 * time.Millisecond},
+		{attempt: 2, base: 400 * time.Millisecond},
+		{attempt: 10, base: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			got := policy.backoff(tt.attempt)
+			if got < tt.base*8/10 || got > tt.base {
+				t.Fatalf("attempt %d: backoff %v outside [%v, %v]", tt.attempt, got, tt.base*8/10, tt.base)
+			}
+		}
+	}
+}
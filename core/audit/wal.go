@@ -0,0 +1,302 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// WALPolicy decides what the Orchestrator does when the write-ahead log
+// reaches its size limit.
+type WALPolicy int
+
+const (
+	// WALBlock stops accepting entries, which blocks Dispatch, until a flush
+	// frees space.
+	WALBlock WALPolicy = iota
+	// WALDropOldest discards the oldest segment, and its entries, to make room.
+	WALDropOldest
+	// WALError rejects new entries with ErrWALFull.
+	WALError
+)
+
+var ErrWALFull = errors.New("audit wal is full")
+
+var errEntryTooLarge = fmt.Errorf("%w: entry exceeds size limit", ErrWALFull)
+
+const (
+	walExt             = ".wal"
+	defaultSegmentSize = 4 << 20
+)
+
+type segment struct {
+	path  string
+	size  int64
+	count int
+}
+
+// wal is a directory of newline-delimited JSON segments holding exactly the
+// entries of the in-memory batch that have not been flushed yet. It is not
+// safe for concurrent use; the Orchestrator guards it with batchLock.
+type wal struct {
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+	policy      WALPolicy
+
+	segments []segment
+	active   *os.File
+	seq      int
+	size     atomic.Int64
+}
+
+func newWAL(dir string, maxBytes int64, policy WALPolicy) *wal {
+	segmentSize := int64(defaultSegmentSize)
+	if maxBytes > 0 && maxBytes/4 < segmentSize {
+		segmentSize = max(maxBytes/4, 1)
+	}
+	return &wal{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		segmentSize: segmentSize,
+		policy:      policy,
+	}
+}
+
+// open replays the segments left by a previous process and compacts them into
+// a fresh segment. A torn trailing record from a crash mid-write is discarded.
+func (w *wal) open() ([]Log, error) {
+	if err := os.MkdirAll(w.dir, 0o750); err != nil {
+		return nil, fmt.Errorf("audit: create wal dir: %w", err)
+	}
+
+	paths, err := w.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var recovered []Log
+	for _, path := range paths {
+		entries, err := readSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		recovered = append(recovered, entries...)
+	}
+
+	// Rewrite into segments numbered after the old ones before removing them,
+	// so a crash during compaction never loses recovered entries.
+	w.seq = lastSeq(paths)
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	for i := range recovered {
+		record, err := encodeRecord(&recovered[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := w.write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("audit: remove wal segment: %w", err)
+		}
+	}
+
+	return recovered, nil
+}
+
+// append persists the entry before it joins the batch. Under WALDropOldest it
+// returns how many of the oldest buffered entries were discarded.
+func (w *wal) append(l *Log) (int, error) {
+	record, err := encodeRecord(l)
+	if err != nil {
+		return 0, err
+	}
+
+	n := int64(len(record))
+	if w.maxBytes > 0 && n > w.maxBytes {
+		return 0, errEntryTooLarge
+	}
+
+	dropped := 0
+	for w.maxBytes > 0 && w.size.Load()+n > w.maxBytes {
+		if w.policy != WALDropOldest {
+			return 0, ErrWALFull
+		}
+		count, err := w.dropOldest()
+		if err != nil {
+			return dropped, err
+		}
+		dropped += count
+	}
+
+	return dropped, w.write(record)
+}
+
+// reset removes every segment. It is called once the batch the segments
+// mirror has been flushed.
+func (w *wal) reset() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	paths, err := w.list()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("audit: remove wal segment: %w", err)
+		}
+	}
+
+	w.segments = nil
+	w.size.Store(0)
+	return w.rotate()
+}
+
+func (w *wal) close() error {
+	if w.active == nil {
+		return nil
+	}
+	err := w.active.Close()
+	w.active = nil
+	return err
+}
+
+func (w *wal) write(record []byte) error {
+	current := &w.segments[len(w.segments)-1]
+	if current.size > 0 && current.size+int64(len(record)) > w.segmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+		current = &w.segments[len(w.segments)-1]
+	}
+
+	if _, err := w.active.Write(record); err != nil {
+		return fmt.Errorf("audit: write wal: %w", err)
+	}
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("audit: sync wal: %w", err)
+	}
+
+	current.size += int64(len(record))
+	current.count++
+	w.size.Add(int64(len(record)))
+	return nil
+}
+
+func (w *wal) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	w.seq++
+	path := filepath.Join(w.dir, fmt.Sprintf("%020d%s", w.seq, walExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("audit: create wal segment: %w", err)
+	}
+
+	w.active = f
+	w.segments = append(w.segments, segment{path: path})
+	return nil
+}
+
+func (w *wal) dropOldest() (int, error) {
+	oldest := w.segments[0]
+	if len(w.segments) == 1 {
+		if err := w.close(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := os.Remove(oldest.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("audit: remove wal segment: %w", err)
+	}
+
+	w.segments = w.segments[1:]
+	w.size.Add(-oldest.size)
+	if len(w.segments) == 0 {
+		if err := w.rotate(); err != nil {
+			return oldest.count, err
+		}
+	}
+	return oldest.count, nil
+}
+
+func (w *wal) list() ([]string, error) {
+	dirEntries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("audit: read wal dir: %w", err)
+	}
+
+	paths := make([]string, 0, len(dirEntries))
+	for _, e := range dirEntries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), walExt) {
+			continue
+		}
+		paths = append(paths, filepath.Join(w.dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func lastSeq(paths []string) int {
+	last := 0
+	for _, path := range paths {
+		seq, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), walExt))
+		if err == nil && seq > last {
+			last = seq
+		}
+	}
+	return last
+}
+
+func readSegment(path string) ([]Log, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open wal segment: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Log
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A record without its trailing newline was torn by a crash.
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("audit: read wal segment: %w", err)
+		}
+
+		var l Log
+		if err := json.Unmarshal(line, &l); err != nil {
+			// Nothing after a corrupt record can be trusted to be in order.
+			return entries, nil
+		}
+		entries = append(entries, l)
+	}
+}
+
+func encodeRecord(l *Log) ([]byte, error) {
+	record, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("audit: encode wal record: %w", err)
+	}
+	return append(record, '\n'), nil
+}
@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOrchestratorWAL_ReplaysAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(1),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(failingProvider{}),
+		WithWAL(dir, 0, WALError),
+	)
+	if err := orch.Dispatch(ctx, Log{Action: "action-1"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if err := orch.Dispatch(ctx, Log{Action: "action-2"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+
+	// Give the loop time to write the second entry, then simulate a crash.
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	spy := &spyProvider{}
+	NewOrchestrator(t.Context(),
+		WithBatchSize(100),
+		WithBatchInterval(100*time.Millisecond),
+		WithProvider(spy),
+		WithWAL(dir, 0, WALError),
+	)
+
+	time.Sleep(300 * time.Millisecond)
+
+	got := spy.logs()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 replayed logs, got %d", len(got))
+	}
+	if got[0].Action != "action-1" || got[1].Action != "action-2" {
+		t.Errorf("unexpected replay order: %q, %q", got[0].Action, got[1].Action)
+	}
+
+	w := newWAL(dir, 0, WALError)
+	recovered, err := w.open()
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	if len(recovered) != 0 {
+		t.Errorf("expected wal to be truncated after flush, got %d entries", len(recovered))
+	}
+}
+
+func TestWAL_IgnoresTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	segment := `{"timestamp":"2026-01-01T00:00:00Z","action":"ok"}` + "\n" + `{"timestamp":"2026-01-0`
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001.wal"), []byte(segment), 0o600); err != nil {
+		t.Fatalf("failed to write segment: %v", err)
+	}
+
+	w := newWAL(dir, 0, WALError)
+	recovered, err := w.open()
+	if err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].Action != "ok" {
+		t.Fatalf("expected only the complete record, got %+v", recovered)
+	}
+
+	if _, err := w.append(&Log{Action: "next"}); err != nil {
+		t.Fatalf("unexpected append error: %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	recovered, err = newWAL(dir, 0, WALError).open()
+	if err != nil {
+		t.Fatalf("unexpected reopen error: %v", err)
+	}
+	if len(recovered) != 2 || recovered[1].Action != "next" {
+		t.Fatalf("expected records appended after compaction to survive, got %+v", recovered)
+	}
+}
+
+func TestWAL_Policies(t *testing.T) {
+	entry := &Log{Action: "entry"}
+	record, err := encodeRecord(entry)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	limit := int64(len(record) * 4)
+
+	t.Run("error", func(t *testing.T) {
+		w := newWAL(t.TempDir(), limit, WALError)
+		if _, err := w.open(); err != nil {
+			t.Fatalf("unexpected open error: %v", err)
+		}
+		for range 4 {
+			if _, err := w.append(entry); err != nil {
+				t.Fatalf("unexpected append error: %v", err)
+			}
+		}
+		if w.size.Load() != limit {
+			t.Fatalf("expected wal to be at its limit, got %d bytes", w.size.Load())
+		}
+		if _, err := w.append(entry); !errors.Is(err, ErrWALFull) {
+			t.Fatalf("expected ErrWALFull, got %v", err)
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		w := newWAL(t.TempDir(), limit, WALDropOldest)
+		if _, err := w.open(); err != nil {
+			t.Fatalf("unexpected open error: %v", err)
+		}
+		for range 4 {
+			if _, err := w.append(entry); err != nil {
+				t.Fatalf("unexpected append error: %v", err)
+			}
+		}
+		dropped, err := w.append(entry)
+		if err != nil {
+			t.Fatalf("unexpected append error: %v", err)
+		}
+		if dropped != 1 {
+			t.Fatalf("expected oldest segment of 1 entry dropped, got %d", dropped)
+		}
+	})
+
+	t.Run("entry too large", func(t *testing.T) {
+		w := newWAL(t.TempDir(), int64(len(record)-1), WALBlock)
+		if _, err := w.open(); err != nil {
+			t.Fatalf("unexpected open error: %v", err)
+		}
+		if _, err := w.append(entry); !errors.Is(err, ErrWALFull) {
+			t.Fatalf("expected ErrWALFull, got %v", err)
+		}
+	})
+}
+
+func TestOrchestratorWAL_DispatchRejectsWhenFull(t *testing.T) {
+	record, err := encodeRecord(&Log{Action: "entry"})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	ctx := t.Context()
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(100),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(failingProvider{}),
+		WithWAL(t.TempDir(), int64(len(record)), WALError),
+	)
+
+	if err := orch.Dispatch(ctx, Log{Action: "entry"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := orch.Dispatch(ctx, Log{Action: "entry"}); !errors.Is(err, ErrWALFull) {
+		t.Fatalf("expected ErrWALFull, got %v", err)
+	}
+}
+
+func TestOrchestratorWAL_DispatchRejectsEntryThatDoesNotFit(t *testing.T) {
+	record, err := encodeRecord(&Log{Action: "entry"})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	ctx := t.Context()
+	provider := &spyProvider{}
+	orch := NewOrchestrator(ctx,
+		WithBatchSize(100),
+		WithBatchInterval(10*time.Minute),
+		WithProvider(provider),
+		WithWAL(t.TempDir(), int64(len(record)*3/2), WALError),
+	)
+
+	if err := orch.Dispatch(ctx, Log{Action: "entry"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	// The WAL is below its limit but has no room for a second record.
+	if err := orch.Dispatch(ctx, Log{Action: "entry"}); !errors.Is(err, ErrWALFull) {
+		t.Fatalf("expected ErrWALFull, got %v", err)
+	}
+
+	if err := orch.Close(ctx); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if got := len(provider.flushed); got != 1 {
+		t.Fatalf("expected the accepted entry to be flushed, got %d", got)
+	}
+	if err := orch.Dispatch(ctx, Log{Action: "entry"}); !errors.Is(err, ErrOrchestratorClosed) {
+		t.Fatalf("expected ErrOrchestratorClosed after close, got %v", err)
+	}
+}
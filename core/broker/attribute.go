@@ -0,0 +1,77 @@
+package broker
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// AttributeType is the data type of an attribute, following the SNS and SQS
+// message attribute types.
+type AttributeType string
+
+const (
+	AttributeTypeString      AttributeType = "String"
+	AttributeTypeNumber      AttributeType = "Number"
+	AttributeTypeStringArray AttributeType = "String.Array"
+	// AttributeTypeBinary values are stored base64-encoded.
+	AttributeTypeBinary AttributeType = "Binary"
+)
+
+// TypedAttributes is implemented by Attributes that record a data type per
+// key, so transports such as SNS can send typed values that subscription
+// filter policies match on.
+type TypedAttributes interface {
+	Attributes
+
+	// SetType records the data type of key.
+	SetType(key string, t AttributeType)
+
+	// Type returns the recorded data type of key, or "" when none was set.
+	Type(key string) AttributeType
+}
+
+// SetAttributeType records the data type of key when attributes implement
+// TypedAttributes and does nothing otherwise.
+func SetAttributeType(attributes Attributes, key string, t AttributeType) {
+	if typed, ok := attributes.(TypedAttributes); ok {
+		typed.SetType(key, t)
+	}
+}
+
+// AttributeTypeOf returns the data type of key: the recorded one when
+// attributes implement TypedAttributes, otherwise String.Array for
+// multi-valued keys and String for the rest.
+func AttributeTypeOf(attributes Attributes, key string) AttributeType {
+	if typed, ok := attributes.(TypedAttributes); ok {
+		if t := typed.Type(key); t != "" {
+			return t
+		}
+	}
+	if len(attributes.Values()[key]) > 1 {
+		return AttributeTypeStringArray
+	}
+	return AttributeTypeString
+}
+
+// AddNumber sets key to a Number attribute, replacing previous values.
+func AddNumber(attributes Attributes, key string, value float64) {
+	attributes.Delete(key)
+	attributes.Add(key, strconv.FormatFloat(value, 'f', -1, 64))
+	SetAttributeType(attributes, key, AttributeTypeNumber)
+}
+
+// AddBinary sets key to a Binary attribute, replacing previous values.
+func AddBinary(attributes Attributes, key string, value []byte) {
+	attributes.Delete(key)
+	attributes.Add(key, base64.StdEncoding.EncodeToString(value))
+	SetAttributeType(attributes, key, AttributeTypeBinary)
+}
+
+// AddStringArray appends values to key and marks it as a String.Array, even
+// when it holds a single value.
+func AddStringArray(attributes Attributes, key string, values ...string) {
+	for _, v := range values {
+		attributes.Add(key, v)
+	}
+	SetAttributeType(attributes, key, AttributeTypeStringArray)
+}
@@ -0,0 +1,21 @@
+package broker
+
+import "testing"
+
+func TestAttributeTypeOf_UntypedAttributes(t *testing.T) {
+	attrs := testAttributes{}
+	attrs.Add("X-Tag", "a")
+	AddNumber(attrs, "X-Amount", 10)
+	AddNumber(attrs, "X-Amount", 12)
+	AddStringArray(attrs, "X-Tags", "a", "b")
+
+	if got := AttributeTypeOf(attrs, "X-Tag"); got != AttributeTypeString {
+		t.Fatalf("expected String, got %s", got)
+	}
+	if got := AttributeTypeOf(attrs, "X-Tags"); got != AttributeTypeStringArray {
+		t.Fatalf("expected multi-valued key to be a String.Array, got %s", got)
+	}
+	if got := AttributeTypeOf(attrs, "X-Amount"); got != AttributeTypeString || attrs.Get("X-Amount") != "12" {
+		t.Fatalf("expected number to replace its value without a recorded type, got %s %v", got, attrs.Values())
+	}
+}
@@ -0,0 +1,102 @@
+package broker
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+// ClaimCheckAttribute carries the BlobStore key of an offloaded payload.
+const ClaimCheckAttribute = "X-Claim-Check"
+
+// DefaultClaimCheckThreshold is the SQS and SNS message size limit. Attributes
+// count towards that limit too, so a lower threshold leaves room for them.
+const DefaultClaimCheckThreshold = 256 * 1024
+
+// BlobStore stores payloads too large to travel through a broker.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// ClaimCheck implements the claim-check pattern: payloads larger than a
+// threshold are stored in a BlobStore and only their key is published.
+// Stored payloads are never deleted; expire them in the store.
+type ClaimCheck struct {
+	store     BlobStore
+	threshold int
+}
+
+// NewClaimCheck offloads payloads larger than threshold bytes to store.
+// A threshold of zero or less uses DefaultClaimCheckThreshold.
+func NewClaimCheck(store BlobStore, threshold int) *ClaimCheck {
+	if threshold <= 0 {
+		threshold = DefaultClaimCheckThreshold
+	}
+	return &ClaimCheck{store: store, threshold: threshold}
+}
+
+// ClaimCheckOption configures ClaimCheck.Encoder.
+type ClaimCheckOption func(*claimCheckOptions)
+
+type claimCheckOptions struct {
+	size func(payload []byte) int
+}
+
+// WithEncodedSize measures payloads with size instead of their length, for
+// transports that expand payloads when encoding them into a message body,
+// such as SQS and SNS base64-encoding binary payloads.
+func WithEncodedSize(size func(payload []byte) int) ClaimCheckOption {
+	return func(o *claimCheckOptions) {
+		o.size = size
+	}
+}
+
+// Encoder wraps next so oversized payloads are stored and replaced by their
+// key, which is also recorded in ClaimCheckAttribute.
+func (c *ClaimCheck) Encoder(ctx context.Context, next Encoder, opts ...ClaimCheckOption) Encoder {
+	return func(m Message) ([]byte, error) {
+		data, err := next(m)
+		if err != nil {
+			return nil, err
+		}
+		return c.Offload(ctx, m, data, opts...)
+	}
+}
+
+// Offload stores data when it is oversized and returns its key, recording it
+// in the ClaimCheckAttribute of m; otherwise data is returned unchanged. It
+// is Encoder for callers that also need the encoded payload itself.
+func (c *ClaimCheck) Offload(ctx context.Context, m Message, data []byte, opts ...ClaimCheckOption) ([]byte, error) {
+	o := &claimCheckOptions{size: func(payload []byte) int { return len(payload) }}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.size(data) <= c.threshold {
+		return data, nil
+	}
+
+	key := rand.Text()
+	if err := c.store.Put(ctx, key, data); err != nil {
+		return nil, fmt.Errorf("failed to offload payload: %w", err)
+	}
+
+	m.Attributes().Delete(ClaimCheckAttribute)
+	m.Attributes().Add(ClaimCheckAttribute, key)
+	return []byte(key), nil
+}
+
+// Rehydrate returns the stored payload of a message carrying a
+// ClaimCheckAttribute, and body unchanged otherwise.
+func (c *ClaimCheck) Rehydrate(ctx context.Context, attributes Attributes, body []byte) ([]byte, error) {
+	key, ok := attributes.Lookup(ClaimCheckAttribute)
+	if !ok {
+		return body, nil
+	}
+
+	data, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offloaded payload %s: %w", key, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testStore struct {
+	blobs map[string][]byte
+	err   error
+}
+
+func (s *testStore) Put(_ context.Context, key string, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.blobs[key] = data
+	return nil
+}
+
+func (s *testStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func TestClaimCheck_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := &testStore{blobs: map[string][]byte{}}
+	claimCheck := NewClaimCheck(store, 64)
+	encode := claimCheck.Encoder(ctx, NewRegistry().Encoder(ContentTypeJSON))
+	decode := NewRegistry().DecoderTarget(order{})
+
+	for _, id := range []string{"o-1", strings.Repeat("x", 128)} {
+		msg := &testMessage{payload: order{ID: id}, attrs: map[string][]string{}}
+
+		body, err := encode(msg)
+		if err != nil {
+			t.Fatalf("unexpected encode error: %v", err)
+		}
+		if _, offloaded := msg.Attributes().Lookup(ClaimCheckAttribute); offloaded != (len(id) > 64) {
+			t.Fatalf("payload of %d bytes: unexpected offload %v", len(id), offloaded)
+		}
+
+		body, err = claimCheck.Rehydrate(ctx, msg.Attributes(), body)
+		if err != nil {
+			t.Fatalf("unexpected rehydrate error: %v", err)
+		}
+		got, err := decode(msg.Attributes(), body)
+		if err != nil {
+			t.Fatalf("unexpected decode error: %v", err)
+		}
+		if got.(*order).ID != id {
+			t.Fatalf("unexpected payload %+v", got)
+		}
+	}
+}
+
+func TestClaimCheck_StoreErrors(t *testing.T) {
+	ctx := context.Background()
+	store := &testStore{blobs: map[string][]byte{}, err: errors.New("unavailable")}
+	claimCheck := NewClaimCheck(store, 1)
+
+	msg := &testMessage{payload: order{ID: "o-1"}, attrs: map[string][]string{}}
+	if _, err := claimCheck.Encoder(ctx, NewRegistry().Encoder(ContentTypeJSON))(msg); !errors.Is(err, store.err) {
+		t.Fatalf("expected store error, got %v", err)
+	}
+
+	attrs := testAttributes{ClaimCheckAttribute: {"missing"}}
+	if _, err := claimCheck.Rehydrate(ctx, attrs, []byte("missing")); err == nil {
+		t.Fatal("expected error for a missing blob")
+	}
+}
+
+func TestClaimCheck_EncodedSize(t *testing.T) {
+	ctx := context.Background()
+	store := &testStore{blobs: map[string][]byte{}}
+	encode := NewClaimCheck(store, 64).Encoder(ctx, NewRegistry().Encoder(ContentTypeJSON),
+		WithEncodedSize(func(payload []byte) int { return 2 * len(payload) }))
+
+	msg := &testMessage{payload: order{ID: strings.Repeat("x", 32)}, attrs: map[string][]string{}}
+	if _, err := encode(msg); err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	if _, ok := msg.Attributes().Lookup(ClaimCheckAttribute); !ok || len(store.blobs) != 1 {
+		t.Fatal("expected the payload to be offloaded by its encoded size")
+	}
+}
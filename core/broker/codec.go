@@ -8,3 +8,7 @@ type Encoder func(Message) ([]byte, error)
 // Decoder is a function type that converts a byte slice into a generic value.
 // It takes a byte slice as input and returns the decoded value as an interface{} along with any error encountered during decoding.
 type Decoder func([]byte) (any, error)
+
+// ContentDecoder is a function type that decodes a payload using the attributes it was published with.
+// It lets subscribers pick a codec and schema version per message instead of assuming a single format.
+type ContentDecoder func(attributes Attributes, data []byte) (any, error)
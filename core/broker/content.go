@@ -0,0 +1,256 @@
+package broker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/common"
+)
+
+// ContentTypeAttribute is the attribute holding the content type of the encoded payload.
+const ContentTypeAttribute = "Content-Type"
+
+// ContentTypeJSON is the content type of the JSON codec, also assumed for
+// messages published without a ContentTypeAttribute.
+const ContentTypeJSON = "application/json"
+
+// ErrUnknownContentType is returned when no codec is registered for a content type.
+var ErrUnknownContentType = errors.New("unknown content type")
+
+// IsPoison reports whether a decode error means the payload can never be
+// decoded, as opposed to one a newer consumer may still understand, such as
+// ErrUnknownContentType or ErrUnsupportedSchemaVersion. Transports discard
+// poison payloads and redeliver the rest.
+func IsPoison(err error) bool {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return true
+	}
+
+	switch common.ClassifyFailureMode(err) {
+	case common.FailureModeDrop, common.FailureModeNonRecoverable:
+		return true
+	default:
+		return false
+	}
+}
+
+// Codec marshals and unmarshals payloads of one content type.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// DocumentCodec is a Codec whose payloads can be decoded generically into a
+// document, which is required to upcast them.
+type DocumentCodec interface {
+	Codec
+	UnmarshalDocument(data []byte) (map[string]any, error)
+}
+
+type jsonCodec struct{}
+
+// JSON returns the application/json codec.
+func JSON() Codec { return jsonCodec{} }
+
+func (jsonCodec) ContentType() string                { return ContentTypeJSON }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// UnmarshalDocument keeps numbers as json.Number so integers above 2^53 are
+// not rounded on their way through an upcast.
+func (jsonCodec) UnmarshalDocument(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+type gzipCodec struct {
+	inner Codec
+}
+
+// Gzip compresses the output of inner. Its content type is the inner one
+// with a "+gzip" suffix, e.g. "application/json+gzip".
+func Gzip(inner Codec) Codec { return gzipCodec{inner: inner} }
+
+func (c gzipCodec) ContentType() string { return c.inner.ContentType() + "+gzip" }
+
+func (c gzipCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	if _, err := gzWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Unmarshal(data []byte, v any) error {
+	decompressed, err := gunzip(data)
+	if err != nil {
+		return err
+	}
+
+	return c.inner.Unmarshal(decompressed, v)
+}
+
+func (c gzipCodec) UnmarshalDocument(data []byte) (map[string]any, error) {
+	inner, ok := c.inner.(DocumentCodec)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUpcastUnsupported, c.ContentType())
+	}
+
+	decompressed, err := gunzip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return inner.UnmarshalDocument(decompressed)
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzReader.Close()
+
+	return io.ReadAll(gzReader)
+}
+
+// Registry maps content types to codecs. Publishers encode with a chosen
+// content type and stamp it on the message; subscribers select the codec
+// from the attribute, so producers can change format without breaking
+// consumers that have the new codec registered.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry returns a Registry holding JSON, gzipped JSON and the given codecs.
+func NewRegistry(codecs ...Codec) *Registry {
+	r := &Registry{codecs: make(map[string]Codec)}
+	r.Register(JSON())
+	r.Register(Gzip(JSON()))
+	for _, c := range codecs {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds c, replacing any codec with the same content type.
+func (r *Registry) Register(c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[c.ContentType()] = c
+}
+
+// Lookup returns the codec for contentType. Media type parameters such as
+// "; charset=utf-8" are ignored and an empty content type means JSON.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[contentType]
+	return c, ok
+}
+
+// Encoder returns an Encoder marshalling payloads with the codec of
+// contentType and setting ContentTypeAttribute on each message.
+func (r *Registry) Encoder(contentType string) Encoder {
+	return func(m Message) ([]byte, error) {
+		c, ok := r.Lookup(contentType)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+		}
+
+		data, err := c.Marshal(m.Payload())
+		if err != nil {
+			return nil, err
+		}
+
+		m.Attributes().Delete(ContentTypeAttribute)
+		m.Attributes().Add(ContentTypeAttribute, c.ContentType())
+
+		return data, nil
+	}
+}
+
+// DecoderOption configures Registry.DecoderTarget.
+type DecoderOption func(*decoderOptions)
+
+type decoderOptions struct {
+	upcaster *Upcaster
+}
+
+// WithUpcaster upgrades payloads published with an older
+// SchemaVersionAttribute to the upcaster's current version before they are
+// decoded into the target type.
+func WithUpcaster(u *Upcaster) DecoderOption {
+	return func(o *decoderOptions) {
+		o.upcaster = u
+	}
+}
+
+// DecoderTarget returns a ContentDecoder unmarshalling each payload into a
+// new *T, where typeof is a T, with the codec of its content type.
+func (r *Registry) DecoderTarget(typeof any, opts ...DecoderOption) ContentDecoder {
+	v := reflect.TypeOf(typeof)
+	o := &decoderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(attributes Attributes, data []byte) (any, error) {
+		contentType := attributes.Get(ContentTypeAttribute)
+		c, ok := r.Lookup(contentType)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownContentType, contentType)
+		}
+
+		if o.upcaster != nil {
+			upcasted, err := o.upcaster.upcast(c, attributes, data)
+			if err != nil {
+				return nil, err
+			}
+			data = upcasted
+		}
+
+		target := reflect.New(v).Interface()
+		if err := c.Unmarshal(data, target); err != nil {
+			return nil, err
+		}
+
+		return target, nil
+	}
+}
@@ -0,0 +1,97 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testMessage struct {
+	payload any
+	attrs   map[string][]string
+}
+
+func (m *testMessage) Payload() any           { return m.payload }
+func (m *testMessage) Attributes() Attributes { return testAttributes(m.attrs) }
+
+type testAttributes map[string][]string
+
+func (a testAttributes) Add(key, value string) { a[key] = append(a[key], value) }
+func (a testAttributes) Get(key string) string { v, _ := a.Lookup(key); return v }
+func (a testAttributes) Delete(key string)     { delete(a, key) }
+func (a testAttributes) Values() map[string][]string {
+	return a
+}
+
+func (a testAttributes) Lookup(key string) (string, bool) {
+	if len(a[key]) == 0 {
+		return "", false
+	}
+	return a[key][0], true
+}
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestRegistry_RoundTrip(t *testing.T) {
+	r := NewRegistry()
+	decode := r.DecoderTarget(order{})
+
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeJSON + "+gzip"} {
+		m := &testMessage{payload: order{ID: "o-1"}, attrs: map[string][]string{}}
+
+		data, err := r.Encoder(contentType)(m)
+		if err != nil {
+			t.Fatalf("%s: unexpected encode error: %v", contentType, err)
+		}
+		if got := m.Attributes().Get(ContentTypeAttribute); got != contentType {
+			t.Fatalf("%s: expected content type attribute, got %q", contentType, got)
+		}
+
+		decoded, err := decode(m.Attributes(), data)
+		if err != nil {
+			t.Fatalf("%s: unexpected decode error: %v", contentType, err)
+		}
+		if got := decoded.(*order); got.ID != "o-1" {
+			t.Fatalf("%s: unexpected payload %+v", contentType, got)
+		}
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	r := NewRegistry()
+
+	if c, ok := r.Lookup(""); !ok || c.ContentType() != ContentTypeJSON {
+		t.Fatal("expected an empty content type to fall back to JSON")
+	}
+	if _, ok := r.Lookup("application/json; charset=utf-8"); !ok {
+		t.Fatal("expected media type parameters to be ignored")
+	}
+
+	attrs := testAttributes{ContentTypeAttribute: {"application/xml"}}
+	_, err := r.DecoderTarget(order{})(attrs, []byte("<order/>"))
+	if !errors.Is(err, ErrUnknownContentType) {
+		t.Fatalf("expected ErrUnknownContentType, got %v", err)
+	}
+}
+
+func TestIsPoison(t *testing.T) {
+	_, syntaxErr := NewRegistry().DecoderTarget(order{})(testAttributes{}, []byte("{not json"))
+	_, typeErr := NewRegistry().DecoderTarget(order{})(testAttributes{}, []byte(`{"id":1}`))
+
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"syntax error":         {syntaxErr, true},
+		"type error":           {typeErr, true},
+		"unknown content type": {fmt.Errorf("%w: application/x-future", ErrUnknownContentType), false},
+		"newer schema version": {fmt.Errorf("%w: 4", ErrUnsupportedSchemaVersion), false},
+	}
+	for name, tc := range cases {
+		if got := IsPoison(tc.err); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+		}
+	}
+}
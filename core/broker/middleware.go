@@ -0,0 +1,39 @@
+package broker
+
+import "context"
+
+// PublisherFunc adapts an ordinary function to the Publisher interface.
+type PublisherFunc func(context.Context, ...Message) error
+
+// Publish calls f(ctx, messages...).
+func (f PublisherFunc) Publish(ctx context.Context, messages ...Message) error {
+	return f(ctx, messages...)
+}
+
+// PublisherMiddleware wraps a Publisher with cross-cutting behavior such as
+// tracing, logging or validation.
+type PublisherMiddleware func(Publisher) Publisher
+
+// ChainPublisher wraps p with mws. The first middleware is the outermost, so
+// it sees messages first and errors last.
+func ChainPublisher(p Publisher, mws ...PublisherMiddleware) Publisher {
+	for i := len(mws) - 1; i >= 0; i-- {
+		p = mws[i](p)
+	}
+	return p
+}
+
+// MessageHandler processes a received Message. A nil error means the message
+// can be committed.
+type MessageHandler func(context.Context, Message) error
+
+// SubscriberMiddleware wraps the handling of received messages.
+type SubscriberMiddleware func(MessageHandler) MessageHandler
+
+// ChainSubscriber wraps h with mws. The first middleware is the outermost.
+func ChainSubscriber(h MessageHandler, mws ...SubscriberMiddleware) MessageHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
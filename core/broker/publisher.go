@@ -1,9 +1,63 @@
 package broker
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 // Publisher is an interface for publishing messages to a message queue or topic.
 type Publisher interface {
 	// Publish publishes a message to a message queue or topic.
 	Publish(context.Context, ...Message) error
 }
+
+// BatchPublisher is a Publisher that reports the outcome of every message.
+type BatchPublisher interface {
+	Publisher
+
+	// PublishBatch publishes messages and returns one entry per message, in order.
+	// The error is only set when nothing could be attempted, e.g. when encoding fails.
+	PublishBatch(context.Context, ...Message) (*PublishResult, error)
+}
+
+// PublishEntry is the outcome of publishing one Message.
+type PublishEntry struct {
+	Message Message
+	// MessageID is the provider's ID of the published message.
+	MessageID string
+	Err       error
+}
+
+// PublishResult holds the outcome of a batch, in the order of the input messages.
+type PublishResult struct {
+	Entries []PublishEntry
+}
+
+// NewPublishResult returns a result with an entry for each of messages.
+func NewPublishResult(messages []Message) *PublishResult {
+	entries := make([]PublishEntry, len(messages))
+	for i, m := range messages {
+		entries[i].Message = m
+	}
+	return &PublishResult{Entries: entries}
+}
+
+// Failed returns the entries that were not published.
+func (r *PublishResult) Failed() []PublishEntry {
+	var failed []PublishEntry
+	for _, e := range r.Entries {
+		if e.Err != nil {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of all failed entries.
+func (r *PublishResult) Err() error {
+	errs := make([]error, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		errs = append(errs, e.Err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,133 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SchemaVersionAttribute is the attribute holding the payload schema version.
+// Messages without it are treated as version 1.
+const SchemaVersionAttribute = "X-Schema-Version"
+
+var (
+	// ErrUnsupportedSchemaVersion is returned for versions below 1 or newer
+	// than the consumer's current version.
+	ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+	// ErrMissingUpcaster is returned when no step upgrades a version.
+	ErrMissingUpcaster = errors.New("missing upcaster")
+	// ErrUpcastUnsupported is returned when an older payload must be upcast
+	// but its codec is not a DocumentCodec, as with protobuf.
+	ErrUpcastUnsupported = errors.New("content type does not support upcasting")
+)
+
+// UpcastFunc upgrades a payload document from one schema version to the next.
+// It may modify and return doc. JSON numbers are json.Number values.
+type UpcastFunc func(doc map[string]any) (map[string]any, error)
+
+// Upcaster upgrades payloads step by step, v1 -> v2 -> ... -> current, so
+// consumers only decode the current shape while older producers keep
+// publishing. It is not safe to Register concurrently with decoding.
+type Upcaster struct {
+	current int
+	steps   map[int]UpcastFunc
+}
+
+// NewUpcaster returns an Upcaster targeting the current schema version.
+func NewUpcaster(current int) *Upcaster {
+	return &Upcaster{current: current, steps: make(map[int]UpcastFunc)}
+}
+
+// Register sets the step upgrading payloads from version from to from+1.
+func (u *Upcaster) Register(from int, fn UpcastFunc) *Upcaster {
+	u.steps[from] = fn
+	return u
+}
+
+// Current returns the version payloads are upgraded to.
+func (u *Upcaster) Current() int {
+	return u.current
+}
+
+// Upcast applies every step from version up to the current version.
+func (u *Upcaster) Upcast(version int, doc map[string]any) (map[string]any, error) {
+	if version < 1 || version > u.current {
+		return nil, fmt.Errorf("%w: %d (current %d)", ErrUnsupportedSchemaVersion, version, u.current)
+	}
+
+	for v := version; v < u.current; v++ {
+		step, ok := u.steps[v]
+		if !ok {
+			return nil, fmt.Errorf("%w: v%d -> v%d", ErrMissingUpcaster, v, v+1)
+		}
+
+		var err error
+		if doc, err = step(doc); err != nil {
+			return nil, fmt.Errorf("upcast v%d -> v%d: %w", v, v+1, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// upcast decodes data as a document with c, upgrades it and re-encodes it.
+// Payloads already at the current version are returned untouched.
+func (u *Upcaster) upcast(c Codec, attributes Attributes, data []byte) ([]byte, error) {
+	version, err := SchemaVersion(attributes)
+	if err != nil {
+		return nil, err
+	}
+	if version == u.current {
+		return data, nil
+	}
+
+	dc, ok := c.(DocumentCodec)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUpcastUnsupported, c.ContentType())
+	}
+
+	doc, err := dc.UnmarshalDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = u.Upcast(version, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Marshal(doc)
+}
+
+// SchemaVersion returns the SchemaVersionAttribute of attributes, or 1 when
+// it is absent.
+func SchemaVersion(attributes Attributes) (int, error) {
+	value, ok := attributes.Lookup(SchemaVersionAttribute)
+	if !ok || value == "" {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedSchemaVersion, value)
+	}
+	return version, nil
+}
+
+// WithSchemaVersion stamps version on every published message that does not
+// carry a SchemaVersionAttribute yet.
+func WithSchemaVersion(version int) PublisherMiddleware {
+	value := strconv.Itoa(version)
+
+	return func(next Publisher) Publisher {
+		return PublisherFunc(func(ctx context.Context, messages ...Message) error {
+			for _, m := range messages {
+				if _, ok := m.Attributes().Lookup(SchemaVersionAttribute); !ok {
+					m.Attributes().Add(SchemaVersionAttribute, value)
+				}
+			}
+			return next.Publish(ctx, messages...)
+		})
+	}
+}
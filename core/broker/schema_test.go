@@ -0,0 +1,175 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// customerV3 is the current shape. v1 had a single "name", v2 split it into
+// "first_name"/"last_name" and v3 nested both under "name".
+type customerV3 struct {
+	ID   string `json:"id"`
+	Name struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	} `json:"name"`
+}
+
+func customerUpcaster() *Upcaster {
+	return NewUpcaster(3).
+		Register(1, func(doc map[string]any) (map[string]any, error) {
+			first, last, _ := strings.Cut(doc["name"].(string), " ")
+			delete(doc, "name")
+			doc["first_name"], doc["last_name"] = first, last
+			return doc, nil
+		}).
+		Register(2, func(doc map[string]any) (map[string]any, error) {
+			doc["name"] = map[string]any{"first": doc["first_name"], "last": doc["last_name"]}
+			delete(doc, "first_name")
+			delete(doc, "last_name")
+			return doc, nil
+		})
+}
+
+func TestUpcaster_MultiStepUpgrade(t *testing.T) {
+	decode := NewRegistry().DecoderTarget(customerV3{}, WithUpcaster(customerUpcaster()))
+
+	cases := map[string]struct {
+		attrs testAttributes
+		body  string
+	}{
+		"v1 without version attribute": {testAttributes{}, `{"id":"c-1","name":"Ada Lovelace"}`},
+		"v2": {
+			testAttributes{SchemaVersionAttribute: {"2"}},
+			`{"id":"c-1","first_name":"Ada","last_name":"Lovelace"}`,
+		},
+		"v3": {
+			testAttributes{SchemaVersionAttribute: {"3"}},
+			`{"id":"c-1","name":{"first":"Ada","last":"Lovelace"}}`,
+		},
+	}
+
+	for name, tc := range cases {
+		decoded, err := decode(tc.attrs, []byte(tc.body))
+		if err != nil {
+			t.Fatalf("%s: unexpected decode error: %v", name, err)
+		}
+		got := decoded.(*customerV3)
+		if got.ID != "c-1" || got.Name.First != "Ada" || got.Name.Last != "Lovelace" {
+			t.Fatalf("%s: unexpected customer %+v", name, got)
+		}
+	}
+}
+
+func TestUpcaster_GzipPayload(t *testing.T) {
+	registry := NewRegistry()
+	m := &testMessage{
+		payload: map[string]any{"id": "c-1", "name": "Ada Lovelace"},
+		attrs:   map[string][]string{},
+	}
+
+	data, err := registry.Encoder(ContentTypeJSON + "+gzip")(m)
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	decoded, err := registry.DecoderTarget(customerV3{}, WithUpcaster(customerUpcaster()))(m.Attributes(), data)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if got := decoded.(*customerV3); got.Name.Last != "Lovelace" {
+		t.Fatalf("unexpected customer %+v", got)
+	}
+}
+
+func TestUpcaster_Errors(t *testing.T) {
+	body := []byte(`{"id":"c-1"}`)
+
+	decode := NewRegistry().DecoderTarget(customerV3{}, WithUpcaster(customerUpcaster()))
+	_, err := decode(testAttributes{SchemaVersionAttribute: {"4"}}, body)
+	if !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Fatalf("expected ErrUnsupportedSchemaVersion for a newer version, got %v", err)
+	}
+
+	incomplete := NewUpcaster(3).Register(2, func(doc map[string]any) (map[string]any, error) { return doc, nil })
+	decode = NewRegistry().DecoderTarget(customerV3{}, WithUpcaster(incomplete))
+	_, err = decode(testAttributes{}, body)
+	if !errors.Is(err, ErrMissingUpcaster) {
+		t.Fatalf("expected ErrMissingUpcaster, got %v", err)
+	}
+}
+
+func TestUpcaster_KeepsLargeIntegers(t *testing.T) {
+	type account struct {
+		ID      int64 `json:"id"`
+		Balance int64 `json:"balance"`
+	}
+
+	upcaster := NewUpcaster(2).Register(1, func(doc map[string]any) (map[string]any, error) {
+		if _, ok := doc["amount"].(json.Number); !ok {
+			return nil, errors.New("expected a json.Number")
+		}
+		doc["balance"] = doc["amount"]
+		delete(doc, "amount")
+		return doc, nil
+	})
+
+	decoded, err := NewRegistry().DecoderTarget(account{}, WithUpcaster(upcaster))(
+		testAttributes{}, []byte(`{"id":9007199254740993,"amount":9223372036854775807}`))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if got := decoded.(*account); got.ID != 9007199254740993 || got.Balance != 9223372036854775807 {
+		t.Fatalf("expected integers to keep their precision, got %+v", got)
+	}
+}
+
+// opaqueCodec stands in for codecs such as protobuf whose payloads cannot be
+// decoded into a generic document.
+type opaqueCodec struct{}
+
+func (opaqueCodec) ContentType() string                { return "application/x-opaque" }
+func (opaqueCodec) Marshal(v any) ([]byte, error)      { return JSON().Marshal(v) }
+func (opaqueCodec) Unmarshal(data []byte, v any) error { return JSON().Unmarshal(data, v) }
+
+func TestUpcaster_RejectsNonDocumentCodecs(t *testing.T) {
+	registry := NewRegistry(opaqueCodec{}, Gzip(opaqueCodec{}))
+	decode := registry.DecoderTarget(customerV3{}, WithUpcaster(customerUpcaster()))
+	body := []byte(`{"id":"c-1","name":{"first":"Ada","last":"Lovelace"}}`)
+
+	for _, contentType := range []string{"application/x-opaque", "application/x-opaque+gzip"} {
+		_, err := decode(testAttributes{ContentTypeAttribute: {contentType}}, []byte("payload"))
+		if !errors.Is(err, ErrUpcastUnsupported) {
+			t.Fatalf("%s: expected ErrUpcastUnsupported, got %v", contentType, err)
+		}
+	}
+
+	current := testAttributes{ContentTypeAttribute: {"application/x-opaque"}, SchemaVersionAttribute: {"3"}}
+	if _, err := decode(current, body); err != nil {
+		t.Fatalf("expected current payloads to decode without upcasting, got %v", err)
+	}
+}
+
+func TestWithSchemaVersion(t *testing.T) {
+	var published []Message
+	pub := ChainPublisher(PublisherFunc(func(_ context.Context, messages ...Message) error {
+		published = messages
+		return nil
+	}), WithSchemaVersion(3))
+
+	stamped := &testMessage{attrs: map[string][]string{}}
+	pinned := &testMessage{attrs: map[string][]string{SchemaVersionAttribute: {"2"}}}
+	if err := pub.Publish(context.Background(), stamped, pinned); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	for i, want := range []int{3, 2} {
+		version, err := SchemaVersion(published[i].Attributes())
+		if err != nil || version != want {
+			t.Fatalf("message %d: expected version %d, got %d (%v)", i, want, version, err)
+		}
+	}
+}
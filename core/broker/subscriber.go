@@ -1,6 +1,15 @@
 package broker
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoMessages is returned, possibly wrapped, by subscribers whose receive
+// came back empty, such as an SQS long poll that timed out. It reports an
+// idle queue rather than a failure.
+var ErrNoMessages = errors.New("no messages available")
 
 // Subscriber is an interface for subscribing to a message queue or topic and receiving messages.
 type Subscriber interface {
@@ -16,3 +25,22 @@ type Subscriber interface {
 	// It takes a context for cancellation and timeout control.
 	Close(context.Context)
 }
+
+// BatchSubscriber is a Subscriber that can receive and commit several messages per round trip.
+type BatchSubscriber interface {
+	Subscriber
+
+	// SubscribeBatch blocks until at least one message is available and returns up to limit messages.
+	// It may return messages together with an error describing messages that could not be decoded.
+	SubscribeBatch(ctx context.Context, limit int) ([]Message, error)
+
+	// CommitBatch acknowledges the successful processing of messages.
+	CommitBatch(ctx context.Context, messages []Message) error
+}
+
+// Extender is implemented by subscribers that can keep a received message hidden from other consumers
+// while it is still being processed, e.g. by extending an SQS visibility timeout.
+type Extender interface {
+	// Extend keeps the Message hidden for at least the given duration from now.
+	Extend(ctx context.Context, message Message, d time.Duration) error
+}
@@ -0,0 +1,49 @@
+package redact
+
+import (
+	"fmt"
+
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// loggerProvider redacts attributes and messages before they reach the
+// wrapped logger.Provider.
+type loggerProvider struct {
+	inner    logger.Provider
+	redactor *Redactor
+}
+
+var _ logger.Provider = (*loggerProvider)(nil)
+
+// NewLoggerProvider wraps inner so every structured attribute goes through r.
+// The log message is used as the action when matching allowlists.
+func NewLoggerProvider(inner logger.Provider, r *Redactor) logger.Provider {
+	return &loggerProvider{inner: inner, redactor: r}
+}
+
+func (p *loggerProvider) Write(level logger.Level, message string, opts ...logger.Option) {
+	cfg := logger.NewConfig(opts...)
+	attributes := p.redactor.Map(message, cfg.Attributes)
+
+	redacted := make([]logger.Option, 0, len(attributes)+1)
+	for key, value := range attributes {
+		redacted = append(redacted, logger.WithValue(key, value))
+	}
+	if cfg.Provider != nil {
+		redacted = append(redacted, logger.WithProvider(cfg.Provider))
+	}
+
+	p.inner.Write(level, p.redactor.String(message), redacted...)
+}
+
+func (p *loggerProvider) WriteF(level logger.Level, template string, args ...any) {
+	if len(args) == 0 {
+		p.inner.WriteF(level, p.redactor.String(template))
+		return
+	}
+	p.inner.WriteF(level, "%s", p.redactor.String(fmt.Sprintf(template, args...)))
+}
+
+func (p *loggerProvider) Enabled(level logger.Level) bool {
+	return p.inner.Enabled(level)
+}
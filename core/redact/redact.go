@@ -0,0 +1,378 @@
+// Package redact masks, hashes or drops sensitive fields from structured
+// payloads such as audit metadata and log attributes.
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+)
+
+// Strategy decides what happens to a value matched by a rule.
+type Strategy int
+
+const (
+	// Mask replaces the value, or the matched part of a string, with Masked.
+	Mask Strategy = iota
+	// Hash replaces the value, or the matched part of a string, with a
+	// SHA-256 (HMAC when a key is set) so equal inputs stay correlatable.
+	Hash
+	// Drop removes the field altogether.
+	Drop
+)
+
+// Masked is the placeholder written by the Mask strategy.
+const Masked = "[REDACTED]"
+
+type keyRule struct {
+	pattern  *regexp.Regexp
+	strategy Strategy
+}
+
+type valueRule struct {
+	detector *regexp.Regexp
+	valid    func(match string) bool
+	strategy Strategy
+}
+
+// Redactor applies key-name rules, value detectors and per-action allowlists.
+// It is safe for concurrent use once built.
+type Redactor struct {
+	keys    []keyRule
+	values  []valueRule
+	allow   map[string]map[string]struct{}
+	hashKey []byte
+}
+
+type Option func(*Redactor)
+
+// WithKeyPattern redacts every value whose key matches pattern, including
+// nested maps and slices under that key.
+func WithKeyPattern(pattern *regexp.Regexp, strategy Strategy) Option {
+	return func(r *Redactor) {
+		r.keys = append(r.keys, keyRule{pattern: pattern, strategy: strategy})
+	}
+}
+
+// WithValueDetector redacts the parts of string values matched by detector,
+// whatever their key. With Drop the whole field is removed.
+func WithValueDetector(detector *regexp.Regexp, strategy Strategy) Option {
+	return func(r *Redactor) {
+		r.values = append(r.values, valueRule{detector: detector, strategy: strategy})
+	}
+}
+
+// WithValidatedDetector is WithValueDetector for matches that valid accepts,
+// such as card numbers passing Luhn, so look-alike values are left alone.
+func WithValidatedDetector(detector *regexp.Regexp, valid func(match string) bool, strategy Strategy) Option {
+	return func(r *Redactor) {
+		r.values = append(r.values, valueRule{detector: detector, valid: valid, strategy: strategy})
+	}
+}
+
+// WithAllowlist exempts the given top-level keys from redaction for one action.
+func WithAllowlist(action string, keys ...string) Option {
+	return func(r *Redactor) {
+		if r.allow == nil {
+			r.allow = make(map[string]map[string]struct{})
+		}
+		if r.allow[action] == nil {
+			r.allow[action] = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			r.allow[action][key] = struct{}{}
+		}
+	}
+}
+
+// WithHashKey turns the Hash strategy into an HMAC-SHA256 keyed with key, so
+// hashes of low-entropy values such as emails cannot be brute-forced.
+func WithHashKey(key []byte) Option {
+	return func(r *Redactor) {
+		r.hashKey = key
+	}
+}
+
+var (
+	// SensitiveKeys matches common credential key names.
+	SensitiveKeys = regexp.MustCompile(
+		`(?i)(password|passwd|secret|token|authorization|api[_-]?key|cookie|session)`)
+	// Email matches email addresses.
+	Email = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+	// CardNumber matches 13 to 19 digit card numbers, optionally grouped.
+	// Combine it with Luhn to skip other long numbers such as IDs.
+	CardNumber = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	// BearerToken matches bearer credentials and JWTs.
+	BearerToken = regexp.MustCompile(
+		`(?i)bearer\s+[a-z0-9\-._~+/]+=*|eyJ[a-zA-Z0-9_\-]+\.[a-zA-Z0-9_\-]+\.[a-zA-Z0-9_\-]+`)
+)
+
+// WithDefaultRules masks credential keys, emails, card numbers and tokens.
+func WithDefaultRules() Option {
+	return func(r *Redactor) {
+		WithKeyPattern(SensitiveKeys, Mask)(r)
+		WithValueDetector(BearerToken, Mask)(r)
+		WithValueDetector(Email, Mask)(r)
+		WithValidatedDetector(CardNumber, Luhn, Mask)(r)
+	}
+}
+
+// Luhn reports whether the digits of number pass the Luhn checksum used by
+// card numbers. Spaces and hyphens are ignored; any other character fails.
+func Luhn(number string) bool {
+	var sum, digits int
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+func New(opts ...Option) *Redactor {
+	r := &Redactor{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Map returns a redacted copy of m; the input is never modified. action
+// selects the allowlist and may be empty. Nested slices and maps of any
+// element type are redacted and copied as []any and map[string]any; structs
+// are redacted through their JSON form, so key rules match JSON field names.
+func (r *Redactor) Map(action string, m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+
+	allowed := r.allow[action]
+	out := make(map[string]any, len(m))
+	for key, value := range m {
+		if _, ok := allowed[key]; ok {
+			out[key] = value
+			continue
+		}
+		if redacted, keep := r.field(key, value); keep {
+			out[key] = redacted
+		}
+	}
+	return out
+}
+
+// String applies the value detectors to s.
+func (r *Redactor) String(s string) string {
+	redacted, keep := r.detect(s)
+	if !keep {
+		return Masked
+	}
+	return redacted
+}
+
+// field redacts one key/value pair and reports whether it should be kept.
+func (r *Redactor) field(key string, value any) (any, bool) {
+	for _, rule := range r.keys {
+		if rule.pattern.MatchString(key) {
+			return r.apply(rule.strategy, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.detect(v)
+	case map[string]any:
+		return r.Map("", v), true
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			if redacted, keep := r.field(key, item); keep {
+				out = append(out, redacted)
+			}
+		}
+		return out, true
+	case []string:
+		return r.strings(v), true
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for k, item := range v {
+			if redacted, keep := r.field(k, item); keep {
+				out[k] = redacted.(string)
+			}
+		}
+		return out, true
+	case map[string][]string:
+		return r.multiMap(v), true
+	case http.Header:
+		return http.Header(r.multiMap(v)), true
+	case error:
+		return r.detect(v.Error())
+	default:
+		return r.reflect(key, value)
+	}
+}
+
+// reflect redacts the slices, arrays, maps and structs that field has no
+// case for. Byte slices and values of other kinds are returned unchanged.
+func (r *Redactor) reflect(key string, value any) (any, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 || (v.Kind() == reflect.Slice && v.IsNil()) {
+			return value, true
+		}
+		out := make([]any, 0, v.Len())
+		for i := range v.Len() {
+			if redacted, keep := r.field(key, v.Index(i).Interface()); keep {
+				out = append(out, redacted)
+			}
+		}
+		return out, true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			return value, true
+		}
+		m := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return r.Map("", m), true
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return value, true
+		}
+		return r.object(value)
+	case reflect.Struct:
+		return r.object(value)
+	default:
+		return value, true
+	}
+}
+
+// object redacts a struct through its JSON form. Structs that do not encode
+// to a JSON object, such as time.Time, are returned unchanged, and structs
+// that fail to encode are masked rather than leaked.
+func (r *Redactor) object(value any) (any, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Masked, true
+	}
+
+	var decoded any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return Masked, true
+	}
+
+	m, ok := decoded.(map[string]any)
+	if !ok {
+		return value, true
+	}
+	return r.Map("", m), true
+}
+
+func (r *Redactor) strings(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, item := range values {
+		if redacted, keep := r.detect(item); keep {
+			out = append(out, redacted)
+		}
+	}
+	return out
+}
+
+// multiMap redacts maps of string lists such as http.Header. Values matched
+// by a key rule become a single masked or hashed entry.
+func (r *Redactor) multiMap(m map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for k, values := range m {
+		redacted, keep := r.field(k, values)
+		if !keep {
+			continue
+		}
+		if s, ok := redacted.(string); ok {
+			out[k] = []string{s}
+			continue
+		}
+		out[k] = redacted.([]string)
+	}
+	return out
+}
+
+func (r *Redactor) detect(s string) (string, bool) {
+	for _, rule := range r.values {
+		if !rule.matches(s) {
+			continue
+		}
+		switch rule.strategy {
+		case Drop:
+			return "", false
+		case Hash:
+			s = rule.replace(s, r.hash)
+		default:
+			s = rule.replace(s, func(string) string { return Masked })
+		}
+	}
+	return s, true
+}
+
+func (rule valueRule) matches(s string) bool {
+	if rule.valid == nil {
+		return rule.detector.MatchString(s)
+	}
+	for _, match := range rule.detector.FindAllString(s, -1) {
+		if rule.valid(match) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule valueRule) replace(s string, with func(string) string) string {
+	return rule.detector.ReplaceAllStringFunc(s, func(match string) string {
+		if rule.valid != nil && !rule.valid(match) {
+			return match
+		}
+		return with(match)
+	})
+}
+
+func (r *Redactor) apply(strategy Strategy, value any) (any, bool) {
+	switch strategy {
+	case Drop:
+		return nil, false
+	case Hash:
+		return r.hash(fmt.Sprint(value)), true
+	default:
+		return Masked, true
+	}
+}
+
+func (r *Redactor) hash(s string) string {
+	if len(r.hashKey) > 0 {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
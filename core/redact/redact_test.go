@@ -0,0 +1,247 @@
+package redact
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+func TestRedactor_Map(t *testing.T) {
+	r := New(
+		WithDefaultRules(),
+		WithKeyPattern(regexp.MustCompile(`(?i)^ssn$`), Drop),
+		WithKeyPattern(regexp.MustCompile(`(?i)^phone$`), Hash),
+		WithAllowlist("user.login", "email"),
+	)
+
+	input := map[string]any{
+		"password": "hunter2",
+		"ssn":      "123-45-6789",
+		"phone":    "+15550100",
+		"email":    "jane@example.com",
+		"note":     "card 4111 1111 1111 1111 used by jane@example.com",
+		"header":   "Bearer abc.def",
+		"status":   200,
+		"nested": map[string]any{
+			"api_key": "k-123",
+			"list":    []any{"bob@example.com", "ok"},
+		},
+	}
+
+	got := r.Map("order.create", input)
+
+	if got["password"] != Masked {
+		t.Errorf("expected password masked, got %v", got["password"])
+	}
+	if _, ok := got["ssn"]; ok {
+		t.Error("expected ssn dropped")
+	}
+	if phone, _ := got["phone"].(string); !strings.HasPrefix(phone, "sha256:") {
+		t.Errorf("expected phone hashed, got %v", got["phone"])
+	}
+	if got["email"] != Masked {
+		t.Errorf("expected email masked outside allowlisted action, got %v", got["email"])
+	}
+	if got["note"] != "card "+Masked+" used by "+Masked {
+		t.Errorf("unexpected note redaction: %v", got["note"])
+	}
+	if got["header"] != Masked {
+		t.Errorf("expected bearer token masked, got %v", got["header"])
+	}
+	if got["status"] != 200 {
+		t.Errorf("expected non-sensitive value untouched, got %v", got["status"])
+	}
+
+	nested := got["nested"].(map[string]any)
+	if nested["api_key"] != Masked {
+		t.Errorf("expected nested api_key masked, got %v", nested["api_key"])
+	}
+	if list := nested["list"].([]any); list[0] != Masked || list[1] != "ok" {
+		t.Errorf("unexpected nested list redaction: %v", list)
+	}
+
+	if input["password"] != "hunter2" {
+		t.Error("expected input to be left untouched")
+	}
+
+	allowed := r.Map("user.login", input)
+	if allowed["email"] != "jane@example.com" {
+		t.Errorf("expected allowlisted email kept, got %v", allowed["email"])
+	}
+	if allowed["password"] != Masked {
+		t.Errorf("expected allowlist to only exempt listed keys, got %v", allowed["password"])
+	}
+}
+
+func TestRedactor_MapStringTypes(t *testing.T) {
+	r := New(WithDefaultRules())
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc.def")
+	header.Add("X-Forwarded-For", "10.0.0.1")
+	header.Add("X-User", "jane@example.com")
+
+	got := r.Map("", map[string]any{
+		"headers": header,
+		"query":   map[string][]string{"session": {"s-1", "s-2"}, "q": {"shoes"}},
+		"labels":  map[string]string{"api_key": "k-123", "owner": "bob@example.com", "team": "core"},
+		"err":     errors.New("user jane@example.com not found"),
+	})
+
+	headers, ok := got["headers"].(http.Header)
+	if !ok {
+		t.Fatalf("expected http.Header to keep its type, got %T", got["headers"])
+	}
+	if headers.Get("Authorization") != Masked || headers.Get("X-User") != Masked {
+		t.Errorf("unexpected header redaction: %v", headers)
+	}
+	if headers.Get("X-Forwarded-For") != "10.0.0.1" {
+		t.Errorf("expected non-sensitive header untouched, got %v", headers)
+	}
+	if header.Get("Authorization") != "Bearer abc.def" {
+		t.Error("expected input header to be left untouched")
+	}
+
+	query := got["query"].(map[string][]string)
+	if len(query["session"]) != 1 || query["session"][0] != Masked || query["q"][0] != "shoes" {
+		t.Errorf("unexpected query redaction: %v", query)
+	}
+
+	labels := got["labels"].(map[string]string)
+	if labels["api_key"] != Masked || labels["owner"] != Masked || labels["team"] != "core" {
+		t.Errorf("unexpected labels redaction: %v", labels)
+	}
+
+	if got["err"] != "user "+Masked+" not found" {
+		t.Errorf("expected error message redacted, got %v", got["err"])
+	}
+}
+
+func TestRedactor_MapTypedValues(t *testing.T) {
+	r := New(WithDefaultRules())
+
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := r.Map("", map[string]any{
+		"items":   []map[string]any{{"token": "t-1", "sku": "a-1"}},
+		"mixed":   []any{map[string]string{"secret": "s-1"}},
+		"login":   credentials{User: "jane", Password: "hunter2"},
+		"pointer": &credentials{User: "bob@example.com"},
+		"owners":  map[string][]credentials{"core": {{User: "jane", Password: "hunter2"}}},
+		"at":      at,
+		"raw":     []byte("token"),
+	})
+
+	items := got["items"].([]any)
+	if item := items[0].(map[string]any); item["token"] != Masked || item["sku"] != "a-1" {
+		t.Errorf("unexpected items redaction: %v", items)
+	}
+	if mixed := got["mixed"].([]any)[0].(map[string]string); mixed["secret"] != Masked {
+		t.Errorf("unexpected mixed redaction: %v", mixed)
+	}
+	if login := got["login"].(map[string]any); login["password"] != Masked || login["user"] != "jane" {
+		t.Errorf("unexpected struct redaction: %v", login)
+	}
+	if pointer := got["pointer"].(map[string]any); pointer["user"] != Masked {
+		t.Errorf("unexpected pointer redaction: %v", pointer)
+	}
+	owners := got["owners"].(map[string]any)["core"].([]any)
+	if owner := owners[0].(map[string]any); owner["password"] != Masked {
+		t.Errorf("unexpected nested struct redaction: %v", owner)
+	}
+	if got["at"] != at {
+		t.Errorf("expected time.Time untouched, got %v", got["at"])
+	}
+	if string(got["raw"].([]byte)) != "token" {
+		t.Errorf("expected byte slice untouched, got %v", got["raw"])
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"4111 1111 1111 1111": true,
+		"5500-0000-0000-0004": true,
+		"4111111111111112":    false,
+		"1234567890123":       false,
+		"41a1111111111111":    false,
+		"":                    false,
+	}
+	for number, want := range tests {
+		if got := Luhn(number); got != want {
+			t.Errorf("Luhn(%q): expected %v, got %v", number, want, got)
+		}
+	}
+
+	r := New(WithDefaultRules())
+	if got := r.String("order 1234567890123 paid with 4111-1111-1111-1111"); got != "order 1234567890123 paid with "+Masked {
+		t.Errorf("expected only the card number masked, got %q", got)
+	}
+}
+
+func TestRedactor_HashKey(t *testing.T) {
+	plain := New(WithValueDetector(Email, Hash))
+	keyed := New(WithValueDetector(Email, Hash), WithHashKey([]byte("secret")))
+
+	a := plain.String("jane@example.com")
+	b := keyed.String("jane@example.com")
+	if a == b {
+		t.Fatal("expected keyed hash to differ from plain hash")
+	}
+	if a != plain.String("jane@example.com") {
+		t.Fatal("expected hashing to be deterministic")
+	}
+}
+
+type captureProvider struct {
+	message    string
+	attributes map[string]any
+	provider   logger.Provider
+}
+
+func (c *captureProvider) Write(_ logger.Level, message string, opts ...logger.Option) {
+	cfg := logger.NewConfig(opts...)
+	c.message = message
+	c.attributes = cfg.Attributes
+	c.provider = cfg.Provider
+}
+
+func (c *captureProvider) WriteF(_ logger.Level, template string, args ...any) {}
+
+func (c *captureProvider) Enabled(_ logger.Level) bool { return true }
+
+func TestLoggerProvider(t *testing.T) {
+	capture := &captureProvider{}
+	provider := NewLoggerProvider(capture, New(WithDefaultRules()))
+
+	provider.Write(logger.InfoLevel, "login by jane@example.com",
+		logger.WithValue("token", "abc"),
+		logger.WithValue("user", "jane"))
+
+	if capture.message != "login by "+Masked {
+		t.Errorf("unexpected message %q", capture.message)
+	}
+	if capture.attributes["token"] != Masked || capture.attributes["user"] != "jane" {
+		t.Errorf("unexpected attributes %v", capture.attributes)
+	}
+}
+
+func TestLoggerProvider_KeepsProviderOption(t *testing.T) {
+	capture := &captureProvider{}
+	override := &captureProvider{}
+	provider := NewLoggerProvider(capture, New(WithDefaultRules()))
+
+	provider.Write(logger.InfoLevel, "login", logger.WithProvider(override))
+
+	if capture.provider != override {
+		t.Errorf("expected the provider option to be forwarded, got %v", capture.provider)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -32,6 +33,7 @@ func (r *AbstractRepository[T]) Save(ctx context.Context, target *T) (*T, error)
 	if err := tx.WithContext(ctx).Create(target).Error; err != nil {
 		return nil, fmt.Errorf("failed to create entity: %w", err)
 	}
+	r.record(ctx, audit.OperationCreate, target, nil, target)
 	return target, nil
 }
 
@@ -45,11 +47,18 @@ func (r *AbstractRepository[T]) Update(ctx context.Context, target *T) (*T, erro
 	if err != nil {
 		tx = r.db
 	}
+	var before *T
+	if r.opts.Audit != nil {
+		before = r.snapshot(ctx, tx, v.GetID())
+	}
 	if err := tx.WithContext(ctx).
 		Where("id = ?", v.GetID()).
 		Updates(target).Error; err != nil {
 		return nil, fmt.Errorf("failed to update entity with ID %d: %w", v.GetID(), err)
 	}
+	if r.opts.Audit != nil {
+		r.record(ctx, audit.OperationUpdate, target, before, r.snapshot(ctx, tx, v.GetID()))
+	}
 	return target, nil
 }
 
@@ -63,15 +72,26 @@ func (r *AbstractRepository[T]) Delete(ctx context.Context, target *T) error {
 	if err != nil {
 		tx = r.db
 	}
+	var before *T
+	if r.opts.Audit != nil {
+		before = r.snapshot(ctx, tx, v.GetID())
+	}
 	query := tx.WithContext(ctx).
 		Model(new(T)).
 		Where("id = ?", v.GetID())
+	var result *gorm.DB
 	if r.opts.SoftDelete {
-		err = query.Update("deleted_at", time.Now()).Error
+		result = query.Update("deleted_at", time.Now())
 	} else {
-		err = query.Delete(new(T)).Error
+		result = query.Delete(new(T))
+	}
+	if result.Error != nil {
+		return result.Error
 	}
-	return err
+	if result.RowsAffected > 0 {
+		r.record(ctx, audit.OperationDelete, target, before, nil)
+	}
+	return nil
 }
 
 // FindOne retrieves a single entity matching the provided filter.
@@ -127,9 +147,16 @@ func (r *AbstractRepository[T]) Find(ctx context.Context, filter *T) ([]*T, erro
 	return out, nil
 }
 
-// Tx executes a function within a database transaction.
+// Tx executes a function within a database transaction. Audit entries
+// recorded inside it are dispatched only once it commits.
 func (r *AbstractRepository[T]) Tx(ctx context.Context, fn func(context.Context) error) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+	ctx, commit := WithAfterCommit(ctx)
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
 		return fn(WithTx(ctx, tx))
-	})
+	}); err != nil {
+		return err
+	}
+
+	commit()
+	return nil
 }
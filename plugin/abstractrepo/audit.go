@@ -0,0 +1,90 @@
+package abstractrepo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/logger"
+	"gorm.io/gorm"
+)
+
+// snapshot loads the current state of the entity for the audit diff. It
+// returns nil when the row cannot be read, which is expected when it does not
+// exist.
+func (r *AbstractRepository[T]) snapshot(ctx context.Context, tx *gorm.DB, id uint) *T {
+	var out T
+	if err := tx.WithContext(ctx).Model(new(T)).Where("id = ?", id).Take(&out).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Of(ctx).DebugS("AbstractRepository::Audit::Snapshot",
+				logger.WithValue("id", id), logger.WithValue("error", err))
+			return nil
+		}
+		logger.Of(ctx).WarnS("AbstractRepository::Audit::Snapshot",
+			logger.WithValue("id", id), logger.WithValue("error", err))
+		return nil
+	}
+	return &out
+}
+
+// record builds the audit entry right away, so later changes to target do not
+// leak into it, and dispatches it once the transaction in ctx commits. target
+// identifies the resource when no state could be loaded. Failures are logged
+// rather than returned because the data change has already been applied.
+func (r *AbstractRepository[T]) record(ctx context.Context, op audit.Operation, target, before, after *T) {
+	if r.opts.Audit == nil {
+		return
+	}
+
+	resource := r.opts.AuditResource
+	if resource == "" {
+		resource = strings.ToLower(reflect.TypeFor[T]().Name())
+	}
+
+	subject := target
+	if after != nil {
+		subject = after
+	} else if before != nil {
+		subject = before
+	}
+
+	entry := &audit.Entry[T]{
+		Actor:        audit.ActorFrom(ctx),
+		ResourceType: resource,
+		ResourceID:   resourceID(subject),
+		Operation:    op,
+		Before:       before,
+		After:        after,
+		IgnoreFields: []string{"updated_at"},
+	}
+	l, err := entry.Log()
+	if err != nil {
+		logger.Of(ctx).ErrorS("AbstractRepository::Audit::Record",
+			logger.WithValue("error", err))
+		return
+	}
+	if rc := common.RequestContextFrom(ctx); rc != nil {
+		l.TraceID = rc.TraceID
+	}
+
+	runAfterCommit(ctx, func() {
+		if err := r.opts.Audit.Dispatch(ctx, l); err != nil {
+			logger.Of(ctx).ErrorS("AbstractRepository::Audit::Record",
+				logger.WithValue("error", err))
+		}
+	})
+}
+
+func resourceID(entity any) string {
+	if v, ok := entity.(interface{ GetExternalID() string }); ok && v.GetExternalID() != "" {
+		return v.GetExternalID()
+	}
+	if v, ok := entity.(interface{ GetID() uint }); ok {
+		return strconv.FormatUint(uint64(v.GetID()), 10)
+	}
+	return ""
+}
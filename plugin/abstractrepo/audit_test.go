@@ -0,0 +1,195 @@
+package abstractrepo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	common.Entity
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type recordingService struct {
+	logs []audit.Log
+}
+
+func (s *recordingService) Dispatch(_ context.Context, log audit.Log) error {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *recordingService) Flush(_ context.Context) error { return nil }
+
+func (s *recordingService) Close(_ context.Context) error { return nil }
+
+func TestAbstractRepository_WithAudit(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &recordingService{}
+	repo, err := NewAbstractRepository[widget](db, WithAudit(svc, ""))
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	ctx := audit.WithActor(context.Background(), "user-1")
+
+	created, err := repo.Save(ctx, &widget{Name: "w", Color: "red"})
+	if err != nil {
+		t.Fatalf("unexpected save error: %v", err)
+	}
+	if _, err := repo.Update(ctx, &widget{Entity: common.Entity{ID: created.ID}, Color: "blue"}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if err := repo.Delete(ctx, &widget{Entity: common.Entity{ID: created.ID}}); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+
+	if len(svc.logs) != 3 {
+		t.Fatalf("expected 3 audit logs, got %d", len(svc.logs))
+	}
+
+	for i, action := range []string{"widget.create", "widget.update", "widget.delete"} {
+		l := svc.logs[i]
+		if l.Action != action {
+			t.Errorf("log %d: expected action %q, got %q", i, action, l.Action)
+		}
+		if l.UserID != "user-1" {
+			t.Errorf("log %d: expected actor %q, got %q", i, "user-1", l.UserID)
+		}
+		if l.Metadata["resource_id"] != created.ExternalID {
+			t.Errorf("log %d: expected resource id %q, got %v", i, created.ExternalID, l.Metadata["resource_id"])
+		}
+	}
+
+	changes := svc.logs[1].Metadata["changes"].(map[string]any)
+	if len(changes) != 1 {
+		t.Fatalf("expected only the color change, got %v", changes)
+	}
+	color := changes["color"].(map[string]any)
+	if color["before"] != "red" || color["after"] != "blue" {
+		t.Errorf("unexpected color change: %v", color)
+	}
+}
+
+func TestAbstractRepository_WithAuditDispatchesAfterCommit(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &recordingService{}
+	repo, err := NewAbstractRepository[widget](db, WithAudit(svc, ""))
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+	err = repo.Tx(ctx, func(ctx context.Context) error {
+		if _, err := repo.Save(ctx, &widget{Name: "w", Color: "red"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected the rollback error, got %v", err)
+	}
+	if len(svc.logs) != 0 {
+		t.Fatalf("expected no audit logs for a rolled back transaction, got %d", len(svc.logs))
+	}
+
+	err = repo.Tx(ctx, func(ctx context.Context) error {
+		if _, err := repo.Save(ctx, &widget{Name: "w", Color: "red"}); err != nil {
+			return err
+		}
+		if len(svc.logs) != 0 {
+			t.Error("expected the audit log to wait for the commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected tx error: %v", err)
+	}
+	if len(svc.logs) != 1 || svc.logs[0].Action != "widget.create" {
+		t.Fatalf("expected the create to be audited after commit, got %+v", svc.logs)
+	}
+}
+
+func TestAbstractRepository_WithAuditSnapshotsBeforeCommit(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &recordingService{}
+	repo, err := NewAbstractRepository[widget](db, WithAudit(svc, ""))
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	ctx := context.Background()
+	err = repo.Tx(ctx, func(ctx context.Context) error {
+		w := &widget{Name: "w", Color: "red"}
+		if _, err := repo.Save(ctx, w); err != nil {
+			return err
+		}
+		w.Color = "blue"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected tx error: %v", err)
+	}
+	if len(svc.logs) != 1 {
+		t.Fatalf("expected 1 audit log, got %d", len(svc.logs))
+	}
+	changes, _ := svc.logs[0].Metadata["changes"].(map[string]any)
+	color, _ := changes["color"].(map[string]any)
+	if color["after"] != "red" {
+		t.Fatalf("expected the saved color in the audit log, got %v", color["after"])
+	}
+}
+
+func TestAbstractRepository_WithAuditSkipsMissingDelete(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := &recordingService{}
+	repo, err := NewAbstractRepository[widget](db, WithAudit(svc, ""))
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	missing := &widget{}
+	missing.ID = 42
+	if err := repo.Delete(context.Background(), missing); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if len(svc.logs) != 0 {
+		t.Fatalf("expected no audit log for a delete that matched no row, got %+v", svc.logs)
+	}
+}
@@ -2,11 +2,15 @@ package abstractrepo
 
 import (
 	"context"
+	"sync"
 
 	"gorm.io/gorm"
 )
 
-type ctxKeyTx struct{}
+type (
+	ctxKeyTx          struct{}
+	ctxKeyAfterCommit struct{}
+)
 
 // WithTx returns a new context with the provided transaction added.
 func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
@@ -22,3 +26,43 @@ func FromContext(ctx context.Context) (*gorm.DB, error) {
 
 	return tx, nil
 }
+
+// afterCommit collects work to run once a transaction commits.
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (a *afterCommit) run() {
+	a.mu.Lock()
+	fns := a.fns
+	a.fns = nil
+	a.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// WithAfterCommit returns a context in which repositories defer work that
+// must only happen once the data is durable, such as audit entries. Call the
+// returned function after the transaction commits; on rollback, drop it.
+func WithAfterCommit(ctx context.Context) (context.Context, func()) {
+	hooks := &afterCommit{}
+	return context.WithValue(ctx, ctxKeyAfterCommit{}, hooks), hooks.run
+}
+
+// runAfterCommit defers fn until the transaction in ctx commits, or runs it
+// right away when ctx carries no WithAfterCommit collector.
+func runAfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(ctxKeyAfterCommit{}).(*afterCommit)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	hooks.fns = append(hooks.fns, fn)
+}
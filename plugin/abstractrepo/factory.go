@@ -1,6 +1,7 @@
 package abstractrepo
 
 import (
+	"github.com/aawadallak/go-core-kit/core/audit"
 	"gorm.io/gorm"
 )
 
@@ -16,6 +17,9 @@ type options struct {
 	NoReturn   bool
 
 	Preloads []string
+
+	Audit         audit.Service
+	AuditResource string
 }
 
 func WithMigrate() Option {
@@ -43,6 +47,18 @@ func WithNoReturn() Option {
 	}
 }
 
+// WithAudit records an audit.Entry with a before/after diff for every Save,
+// Update and Delete. resourceType defaults to the lower-cased entity type
+// name. Inside Tx, or any transaction whose context comes from
+// WithAfterCommit, entries are dispatched only after the commit; otherwise
+// they are dispatched as soon as the statement succeeds.
+func WithAudit(svc audit.Service, resourceType string) Option {
+	return func(o *options) {
+		o.Audit = svc
+		o.AuditResource = resourceType
+	}
+}
+
 func NewAbstractPaginatedRepository[T, E any](
 	db *gorm.DB, opts ...Option) (*AbstractPaginatedRepository[T, E], error) {
 	options := &options{
@@ -0,0 +1,116 @@
+// Package auditbroker provides an audit.Provider that publishes entries
+// through any broker.Publisher.
+package auditbroker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// Message attributes set on every published entry so consumers can route
+// without decoding the payload. Empty values are omitted.
+const (
+	AttributeAction  = "X-Audit-Action"
+	AttributeUserID  = "X-Audit-User-ID"
+	AttributeTraceID = "X-Audit-Trace-ID"
+)
+
+// defaultChunkSize matches the SQS and SNS batch limit.
+const defaultChunkSize = 10
+
+// MessageFactory wraps an entry in the broker.Message type expected by the
+// publisher, e.g. func(l audit.Log) broker.Message { return sqs.NewMessage(l, queueURL) }.
+type MessageFactory func(l audit.Log) broker.Message
+
+type Provider struct {
+	publisher  broker.Publisher
+	newMessage MessageFactory
+	chunkSize  int
+	closed     atomic.Bool
+}
+
+var _ audit.Provider = (*Provider)(nil)
+
+type Option func(*Provider)
+
+// WithChunkSize sets how many messages are passed to a single Publish call.
+func WithChunkSize(size int) Option {
+	return func(p *Provider) {
+		p.chunkSize = size
+	}
+}
+
+func NewProvider(publisher broker.Publisher, factory MessageFactory, opts ...Option) (*Provider, error) {
+	if publisher == nil {
+		return nil, errors.New("broker audit provider requires a non-nil publisher")
+	}
+	if factory == nil {
+		return nil, errors.New("broker audit provider requires a non-nil message factory")
+	}
+
+	p := &Provider{
+		publisher:  publisher,
+		newMessage: factory,
+		chunkSize:  defaultChunkSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.chunkSize <= 0 {
+		p.chunkSize = defaultChunkSize
+	}
+
+	return p, nil
+}
+
+// Flush implements audit.Provider. Entries are published in chunks and the
+// flush stops at the first failing chunk; chunks published before it are
+// sent again when the batch is retried.
+func (p *Provider) Flush(ctx context.Context, entries ...audit.Log) error {
+	if p.closed.Load() {
+		return audit.ErrProviderClosed
+	}
+
+	for start := 0; start < len(entries); start += p.chunkSize {
+		end := min(start+p.chunkSize, len(entries))
+
+		messages := make([]broker.Message, 0, end-start)
+		for i := start; i < end; i++ {
+			messages = append(messages, p.toMessage(entries[i]))
+		}
+
+		if err := p.publisher.Publish(ctx, messages...); err != nil {
+			return fmt.Errorf("auditbroker: publish entries %d-%d: %w", start, end-1, err)
+		}
+	}
+
+	return nil
+}
+
+// Close implements audit.Provider. The publisher is owned by the caller.
+func (p *Provider) Close(_ context.Context) error {
+	p.closed.Store(true)
+	return nil
+}
+
+func (p *Provider) toMessage(l audit.Log) broker.Message {
+	msg := p.newMessage(l)
+	attrs := msg.Attributes()
+
+	for key, value := range map[string]string{
+		AttributeAction:  l.Action,
+		AttributeUserID:  l.UserID,
+		AttributeTraceID: l.TraceID,
+	} {
+		if value != "" {
+			attrs.Add(key, value)
+		}
+	}
+
+	return msg
+}
@@ -0,0 +1,111 @@
+package auditbroker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+type testAttributes map[string][]string
+
+func (a testAttributes) Add(key, value string) { a[key] = append(a[key], value) }
+func (a testAttributes) Get(key string) string {
+	v, _ := a.Lookup(key)
+	return v
+}
+
+func (a testAttributes) Lookup(key string) (string, bool) {
+	if len(a[key]) == 0 {
+		return "", false
+	}
+	return a[key][0], true
+}
+func (a testAttributes) Delete(key string)           { delete(a, key) }
+func (a testAttributes) Values() map[string][]string { return a }
+
+type testMessage struct {
+	payload any
+	attrs   testAttributes
+}
+
+func (m *testMessage) Payload() any                  { return m.payload }
+func (m *testMessage) Attributes() broker.Attributes { return m.attrs }
+
+type spyPublisher struct {
+	calls [][]broker.Message
+	err   error
+}
+
+func (s *spyPublisher) Publish(_ context.Context, messages ...broker.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.calls = append(s.calls, messages)
+	return nil
+}
+
+func newTestMessage(l audit.Log) broker.Message {
+	return &testMessage{payload: l, attrs: testAttributes{"X-Message-Queue": {"audit"}}}
+}
+
+func TestFlush_ChunksAndAttributes(t *testing.T) {
+	publisher := &spyPublisher{}
+	provider, err := NewProvider(publisher, newTestMessage)
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	entries := make([]audit.Log, 0, 25)
+	for range 25 {
+		entries = append(entries, audit.Log{Action: "admin.user.create", UserID: "u-1"})
+	}
+
+	if err := provider.Flush(context.Background(), entries...); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if len(publisher.calls) != 3 {
+		t.Fatalf("expected 3 publish calls, got %d", len(publisher.calls))
+	}
+	for i, want := range []int{10, 10, 5} {
+		if got := len(publisher.calls[i]); got != want {
+			t.Errorf("chunk %d: expected %d messages, got %d", i, want, got)
+		}
+	}
+
+	attrs := publisher.calls[0][0].Attributes()
+	if attrs.Get(AttributeAction) != "admin.user.create" || attrs.Get(AttributeUserID) != "u-1" {
+		t.Errorf("unexpected attributes: %v", attrs.Values())
+	}
+	if _, ok := attrs.Lookup(AttributeTraceID); ok {
+		t.Error("expected empty trace ID to be omitted")
+	}
+	if attrs.Get("X-Message-Queue") != "audit" {
+		t.Error("expected factory attributes to be preserved")
+	}
+	if _, ok := publisher.calls[0][0].Payload().(audit.Log); !ok {
+		t.Error("expected audit.Log payload")
+	}
+}
+
+func TestFlush_Errors(t *testing.T) {
+	cause := errors.New("throttled")
+	provider, err := NewProvider(&spyPublisher{err: cause}, newTestMessage)
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	if err := provider.Flush(context.Background(), audit.Log{Action: "a"}); !errors.Is(err, cause) {
+		t.Fatalf("expected publish error, got %v", err)
+	}
+
+	if err := provider.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	if err := provider.Flush(context.Background(), audit.Log{Action: "a"}); !errors.Is(err, audit.ErrProviderClosed) {
+		t.Fatalf("expected ErrProviderClosed, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package auditgorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	gormpkg "gorm.io/gorm"
+)
+
+const iteratorBatchSize = 500
+
+// Head returns the hash of the most recently inserted entry, or an empty
+// string when the table is empty. Pass it to audit.WithChainHead on startup.
+func (p *Provider) Head(ctx context.Context) (string, error) {
+	var row model
+	err := p.db.WithContext(ctx).Table(p.table).Order("id DESC").Limit(1).Take(&row).Error
+	if errors.Is(err, gormpkg.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch audit chain head: %w", err)
+	}
+	return row.Hash, nil
+}
+
+// Iterate returns an audit.Iterator over entries in insertion order,
+// suitable for audit.Verify. The range runs from the first entry with
+// timestamp >= from to the last one with timestamp < to, and includes every
+// entry inserted in between whatever its timestamp, so the chain has no gaps.
+// Zero bounds are ignored.
+func (p *Provider) Iterate(from, to time.Time) audit.Iterator {
+	return &iterator{provider: p, filter: Filter{From: from, To: to}}
+}
+
+type iterator struct {
+	provider *Provider
+	filter   Filter
+	bounded  bool
+	lastID   uint64
+	maxID    uint64
+	buf      []model
+	done     bool
+}
+
+func (it *iterator) Next(ctx context.Context) (audit.Log, bool, error) {
+	if !it.bounded {
+		if err := it.bounds(ctx); err != nil {
+			return audit.Log{}, false, err
+		}
+	}
+
+	if len(it.buf) == 0 {
+		if it.done {
+			return audit.Log{}, false, nil
+		}
+		if err := it.fetch(ctx); err != nil {
+			return audit.Log{}, false, err
+		}
+		if len(it.buf) == 0 {
+			return audit.Log{}, false, nil
+		}
+	}
+
+	row := it.buf[0]
+	it.buf = it.buf[1:]
+	it.lastID = row.ID
+
+	l, err := toLog(&row)
+	if err != nil {
+		return audit.Log{}, false, err
+	}
+	return l, true, nil
+}
+
+// bounds resolves the timestamp range into the ids of its first and last
+// entries.
+func (it *iterator) bounds(ctx context.Context) error {
+	var ids struct {
+		First *uint64
+		Last  *uint64
+	}
+	err := applyFilter(it.provider.db.WithContext(ctx).Table(it.provider.table), &it.filter).
+		Select("MIN(id) AS first, MAX(id) AS last").
+		Scan(&ids).Error
+	if err != nil {
+		return fmt.Errorf("failed to resolve audit log range: %w", err)
+	}
+
+	it.bounded = true
+	if ids.First == nil || ids.Last == nil {
+		it.done = true
+		return nil
+	}
+	it.lastID = *ids.First - 1
+	it.maxID = *ids.Last
+	return nil
+}
+
+func (it *iterator) fetch(ctx context.Context) error {
+	var rows []model
+	err := it.provider.db.WithContext(ctx).Table(it.provider.table).
+		Where("id > ? AND id <= ?", it.lastID, it.maxID).
+		Order("id ASC").
+		Limit(iteratorBatchSize).
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to iterate audit logs: %w", err)
+	}
+
+	it.buf = rows
+	it.done = len(rows) < iteratorBatchSize
+	return nil
+}
@@ -0,0 +1,197 @@
+// Package auditgorm provides a GORM-backed audit.Provider that persists entries
+// into an append-only table.
+package auditgorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	gormpkg "gorm.io/gorm"
+)
+
+type model struct {
+	ID        uint64    `gorm:"column:id;primaryKey;autoIncrement"`
+	Timestamp time.Time `gorm:"column:timestamp;not null"`
+	TraceID   string    `gorm:"column:trace_id;not null"`
+	UserID    string    `gorm:"column:user_id;not null"`
+	Action    string    `gorm:"column:action;not null"`
+	Metadata  []byte    `gorm:"column:metadata;type:jsonb"`
+	PrevHash  string    `gorm:"column:prev_hash;not null"`
+	Hash      string    `gorm:"column:hash;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+type Provider struct {
+	db     *gormpkg.DB
+	table  string
+	closed atomic.Bool
+}
+
+var _ audit.Provider = (*Provider)(nil)
+
+func NewProvider(db *gormpkg.DB, table string) (*Provider, error) {
+	if db == nil {
+		return nil, errors.New("gorm audit provider requires a non-nil db")
+	}
+	if table == "" {
+		table = "audit_logs"
+	}
+	return &Provider{db: db, table: table}, nil
+}
+
+// EnsureSchema creates the audit table, its lookup indexes and triggers that
+// reject UPDATE, DELETE and TRUNCATE statements so the trail stays append-only.
+func (p *Provider) EnsureSchema(ctx context.Context) error {
+	table := quoteIdentifier(p.table)
+	name := identifier(p.table)
+	fn := quoteIdentifier(name + "_append_only")
+
+	statements := []string{
+		fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	timestamp TIMESTAMPTZ NOT NULL,
+	trace_id TEXT NOT NULL DEFAULT '',
+	user_id TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL,
+	metadata JSONB,
+	prev_hash TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);`, table),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';`, table),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT '';`, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (timestamp);`, quoteIdentifier(name+"_timestamp_idx"), table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (user_id, timestamp);`, quoteIdentifier(name+"_user_id_idx"), table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (trace_id);`, quoteIdentifier(name+"_trace_id_idx"), table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (action text_pattern_ops);`, quoteIdentifier(name+"_action_idx"), table),
+		fmt.Sprintf(`
+CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit table %% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;`, fn),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s;`, fn, table),
+		fmt.Sprintf(`
+CREATE TRIGGER %s
+	BEFORE UPDATE OR DELETE ON %s
+	FOR EACH ROW EXECUTE FUNCTION %s();`, fn, table, fn),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s;`, quoteIdentifier(name+"_append_only_truncate"), table),
+		fmt.Sprintf(`
+CREATE TRIGGER %s
+	BEFORE TRUNCATE ON %s
+	FOR EACH STATEMENT EXECUTE FUNCTION %s();`, quoteIdentifier(name+"_append_only_truncate"), table, fn),
+	}
+
+	for _, stmt := range statements {
+		if err := p.db.WithContext(ctx).Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush implements audit.Provider by writing every entry with a single
+// multi-row INSERT.
+func (p *Provider) Flush(ctx context.Context, entries ...audit.Log) error {
+	if p.closed.Load() {
+		return audit.ErrProviderClosed
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]model, 0, len(entries))
+	for i := range entries {
+		row, err := toModel(&entries[i], now)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	return p.db.WithContext(ctx).Table(p.table).Create(&rows).Error
+}
+
+// Close implements audit.Provider. The underlying db is owned by the caller
+// and is left open.
+func (p *Provider) Close(_ context.Context) error {
+	p.closed.Store(true)
+	return nil
+}
+
+func toModel(l *audit.Log, now time.Time) (model, error) {
+	m := model{
+		Timestamp: l.Timestamp,
+		TraceID:   l.TraceID,
+		UserID:    l.UserID,
+		Action:    l.Action,
+		PrevHash:  l.PrevHash,
+		Hash:      l.Hash,
+		CreatedAt: now,
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = now
+	}
+
+	if len(l.Metadata) > 0 {
+		metadata, err := json.Marshal(l.Metadata)
+		if err != nil {
+			return model{}, fmt.Errorf("auditgorm: marshal metadata: %w", err)
+		}
+		m.Metadata = metadata
+	}
+
+	return m, nil
+}
+
+func toLog(m *model) (audit.Log, error) {
+	l := audit.Log{
+		Timestamp: m.Timestamp,
+		TraceID:   m.TraceID,
+		UserID:    m.UserID,
+		Action:    m.Action,
+		PrevHash:  m.PrevHash,
+		Hash:      m.Hash,
+	}
+
+	if len(m.Metadata) > 0 {
+		// Numbers are kept as json.Number so large integers survive exactly
+		// and the entry still matches its chain hash.
+		decoder := json.NewDecoder(bytes.NewReader(m.Metadata))
+		decoder.UseNumber()
+		if err := decoder.Decode(&l.Metadata); err != nil {
+			return audit.Log{}, fmt.Errorf("auditgorm: unmarshal metadata: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// identifier derives a safe prefix for index, function and trigger names from
+// a possibly schema-qualified table name.
+func identifier(table string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, table)
+}
+
+// quoteIdentifier quotes each part of a possibly schema-qualified name.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,314 @@
+package auditgorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	gormpkg "gorm.io/gorm"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+	db, err := gormpkg.Open(sqlite.Open(":memory:"), &gormpkg.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+
+	provider, err := NewProvider(db, "")
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	// EnsureSchema is PostgreSQL specific; migrate the model for sqlite instead.
+	if err := db.Table(provider.table).AutoMigrate(&model{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return provider
+}
+
+func TestFlush_PersistsEntries(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	err := provider.Flush(ctx,
+		audit.Log{Timestamp: base, UserID: "u-1", TraceID: "t-1", Action: "admin.user.create",
+			Metadata: map[string]any{"target": "u-9"}},
+		audit.Log{Timestamp: base.Add(time.Minute), UserID: "u-1", TraceID: "t-2", Action: "admin.user.delete"},
+		audit.Log{Timestamp: base.Add(2 * time.Minute), UserID: "u-2", TraceID: "t-3", Action: "GET /items"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	page, err := provider.FindAll(ctx, &repository.PaginationQuery[Filter]{})
+	if err != nil {
+		t.Fatalf("unexpected find error: %v", err)
+	}
+	if page.Total != 3 {
+		t.Fatalf("expected 3 entries, got %d", page.Total)
+	}
+	if page.Data[0].Action != "GET /items" {
+		t.Fatalf("expected newest entry first, got %q", page.Data[0].Action)
+	}
+	if got := page.Data[2].Metadata["target"]; got != "u-9" {
+		t.Fatalf("expected metadata round trip, got %v", got)
+	}
+}
+
+func TestFindAll_Filters(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	err := provider.Flush(ctx,
+		audit.Log{Timestamp: base, UserID: "u-1", TraceID: "t-1", Action: "admin.user.create"},
+		audit.Log{Timestamp: base.Add(time.Minute), UserID: "u-1", TraceID: "t-1", Action: "admin_x"},
+		audit.Log{Timestamp: base.Add(2 * time.Minute), UserID: "u-2", TraceID: "t-2", Action: "admin.role.grant"},
+		audit.Log{Timestamp: base.Add(3 * time.Minute), UserID: "u-1", TraceID: "t-3", Action: "GET /items"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{name: "by user", filter: Filter{UserID: "u-1"}, want: 3},
+		{name: "by trace", filter: Filter{TraceID: "t-1"}, want: 2},
+		{name: "by action prefix escapes wildcards", filter: Filter{ActionPrefix: "admin."}, want: 2},
+		{name: "by time range", filter: Filter{From: base.Add(time.Minute), To: base.Add(3 * time.Minute)}, want: 2},
+		{name: "combined", filter: Filter{UserID: "u-1", ActionPrefix: "admin"}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			page, err := provider.FindAll(ctx, &repository.PaginationQuery[Filter]{Query: &filter})
+			if err != nil {
+				t.Fatalf("unexpected find error: %v", err)
+			}
+			if page.Total != tt.want {
+				t.Fatalf("expected %d entries, got %d", tt.want, page.Total)
+			}
+		})
+	}
+}
+
+func TestFindAll_Pagination(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := make([]audit.Log, 0, 5)
+	for i := range 5 {
+		entries = append(entries, audit.Log{Timestamp: base.Add(time.Duration(i) * time.Minute), Action: "a"})
+	}
+	if err := provider.Flush(ctx, entries...); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	page, err := provider.FindAll(ctx, &repository.PaginationQuery[Filter]{
+		Page:    3,
+		PerPage: 2,
+		Order:   repository.OrderBy{Field: "timestamp", Direction: "asc"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected find error: %v", err)
+	}
+	if page.Pages != 3 || len(page.Data) != 1 {
+		t.Fatalf("expected last page with 1 of 3 pages, got pages=%d len=%d", page.Pages, len(page.Data))
+	}
+	if !page.Data[0].Timestamp.Equal(base.Add(4 * time.Minute)) {
+		t.Fatalf("unexpected entry on last page: %v", page.Data[0].Timestamp)
+	}
+
+	_, err = provider.FindAll(ctx, &repository.PaginationQuery[Filter]{
+		Order: repository.OrderBy{Field: "metadata; DROP TABLE audit_logs"},
+	})
+	if err == nil {
+		t.Fatal("expected error for unsupported order field")
+	}
+}
+
+func TestFlush_AfterClose(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	if err := provider.Close(ctx); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	err := provider.Flush(ctx, audit.Log{Action: "late"})
+	if !errors.Is(err, audit.ErrProviderClosed) {
+		t.Fatalf("expected ErrProviderClosed, got %v", err)
+	}
+}
+
+func TestIterate_VerifiesChain(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 123456789, time.UTC)
+
+	chain := audit.NewChainProvider(provider)
+	for i := range 3 {
+		err := chain.Flush(ctx, audit.Log{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			UserID:    "u-1",
+			Action:    "admin.user.update",
+			Metadata:  map[string]any{"attempt": i},
+		})
+		if err != nil {
+			t.Fatalf("unexpected flush error: %v", err)
+		}
+	}
+
+	head, err := provider.Head(ctx)
+	if err != nil {
+		t.Fatalf("unexpected head error: %v", err)
+	}
+	if head != chain.Head() {
+		t.Fatalf("expected stored head %q, got %q", chain.Head(), head)
+	}
+
+	if err := audit.Verify(ctx, provider.Iterate(time.Time{}, time.Time{})); err != nil {
+		t.Fatalf("expected valid chain, got %v", err)
+	}
+
+	// Simulate an out-of-band edit that bypassed the append-only trigger.
+	if err := provider.db.Table(provider.table).Where("id = ?", 2).
+		Update("action", "admin.user.read").Error; err != nil {
+		t.Fatalf("failed to tamper: %v", err)
+	}
+
+	err = audit.Verify(ctx, provider.Iterate(time.Time{}, time.Time{}))
+	if !errors.Is(err, audit.ErrChainBroken) {
+		t.Fatalf("expected ErrChainBroken, got %v", err)
+	}
+}
+
+func TestIterate_VerifiesLargeIntegers(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	chain := audit.NewChainProvider(provider)
+	err := chain.Flush(ctx, audit.Log{
+		Timestamp: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Action:    "payment.capture",
+		Metadata:  map[string]any{"amount": int64(9007199254740993), "rate": 0.1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	if err := audit.Verify(ctx, provider.Iterate(time.Time{}, time.Time{})); err != nil {
+		t.Fatalf("expected valid chain, got %v", err)
+	}
+}
+
+func TestIterate_WalksIDsBetweenTimestampBounds(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The middle entry was stamped before the range by a lagging clock but
+	// inserted inside it, so Verify must still see it.
+	chain := audit.NewChainProvider(provider)
+	for _, ts := range []time.Time{base.Add(-time.Hour), base, base.Add(-2 * time.Hour), base.Add(time.Minute), base.Add(time.Hour)} {
+		if err := chain.Flush(ctx, audit.Log{Timestamp: ts, Action: "admin.user.update"}); err != nil {
+			t.Fatalf("unexpected flush error: %v", err)
+		}
+	}
+
+	it := provider.Iterate(base, base.Add(30*time.Minute))
+	var seen []string
+	for {
+		l, ok, err := it.Next(ctx)
+		if err != nil {
+			t.Fatalf("unexpected iterate error: %v", err)
+		}
+		if !ok {
+			break
+		}
+		seen = append(seen, l.Timestamp.UTC().Format(time.Kitchen))
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected the 3 entries inserted between the bounds, got %v", seen)
+	}
+
+	if err := audit.Verify(ctx, provider.Iterate(base, base.Add(30*time.Minute))); err != nil {
+		t.Fatalf("expected valid chain, got %v", err)
+	}
+	if err := audit.Verify(ctx, provider.Iterate(base.Add(2*time.Hour), time.Time{})); err != nil {
+		t.Fatalf("expected an empty range to verify, got %v", err)
+	}
+}
+
+// ensureSchemaSQL runs EnsureSchema against a dry-run PostgreSQL session and
+// returns the statements it would execute.
+func ensureSchemaSQL(t *testing.T, table string) string {
+	t.Helper()
+	db, err := gormpkg.Open(postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gormpkg.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open dry-run db: %v", err)
+	}
+
+	var statements []string
+	err = db.Callback().Raw().After("gorm:raw").Register("capture", func(tx *gormpkg.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	provider, err := NewProvider(db, table)
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+	if err := provider.EnsureSchema(context.Background()); err != nil {
+		t.Fatalf("unexpected ensure schema error: %v", err)
+	}
+
+	return strings.Join(statements, "\n")
+}
+
+func TestEnsureSchema(t *testing.T) {
+	sql := ensureSchemaSQL(t, "audit.logs")
+
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "audit"."logs"`,
+		`CREATE INDEX IF NOT EXISTS "audit_logs_timestamp_idx" ON "audit"."logs"`,
+		`CREATE OR REPLACE FUNCTION "audit_logs_append_only"()`,
+		`BEFORE UPDATE OR DELETE ON "audit"."logs"`,
+		`BEFORE TRUNCATE ON "audit"."logs"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected schema to contain %q, got:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "%!") {
+		t.Errorf("unexpected formatting error in schema:\n%s", sql)
+	}
+}
+
+func TestEnsureSchema_QuotesTableName(t *testing.T) {
+	sql := ensureSchemaSQL(t, `logs"; DROP TABLE users; --`)
+
+	if !strings.Contains(sql, `CREATE TABLE IF NOT EXISTS "logs""; DROP TABLE users; --"`) {
+		t.Fatalf("expected the table name to be quoted, got:\n%s", sql)
+	}
+	if strings.Contains(sql, `"logs";`) {
+		t.Fatalf("expected the table name not to escape its quotes, got:\n%s", sql)
+	}
+}
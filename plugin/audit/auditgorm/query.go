@@ -0,0 +1,143 @@
+package auditgorm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/repository"
+	gormpkg "gorm.io/gorm"
+)
+
+// Filter narrows the audit trail returned by FindAll. Zero-valued fields are ignored.
+type Filter struct {
+	UserID       string
+	TraceID      string
+	ActionPrefix string
+	From         time.Time // inclusive
+	To           time.Time // exclusive
+}
+
+const (
+	defaultPerPage = 50
+	defaultPage    = 1
+)
+
+// sortable lists the columns accepted in PaginationQuery.Order.Field.
+var sortable = map[string]struct{}{
+	"id":        {},
+	"timestamp": {},
+	"action":    {},
+	"user_id":   {},
+	"trace_id":  {},
+}
+
+var _ repository.AbstractPaginatedRepository[audit.Log, Filter] = (*Provider)(nil)
+
+// FindAll pages through the audit trail. Results default to newest first.
+func (p *Provider) FindAll(
+	ctx context.Context, query *repository.PaginationQuery[Filter]) (*repository.Pagination[audit.Log], error) {
+	if query == nil {
+		query = &repository.PaginationQuery[Filter]{}
+	}
+
+	instance := p.db.WithContext(ctx).Table(p.table)
+	if query.Query != nil {
+		instance = applyFilter(instance, query.Query)
+	}
+
+	var count int64
+	if err := instance.Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	order, err := orderClause(query.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	perPage := query.PerPage
+	page := query.Page
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	if page <= 0 {
+		page = defaultPage
+	}
+
+	var rows []model
+	if err := instance.
+		Order(order).
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch audit logs: %w", err)
+	}
+
+	data := make([]audit.Log, 0, len(rows))
+	for i := range rows {
+		l, err := toLog(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, l)
+	}
+
+	return &repository.Pagination[audit.Log]{
+		Data:  data,
+		Page:  page,
+		Pages: int(math.Ceil(float64(count) / float64(perPage))),
+		Total: int(count),
+	}, nil
+}
+
+func applyFilter(db *gormpkg.DB, f *Filter) *gormpkg.DB {
+	if f.UserID != "" {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.TraceID != "" {
+		db = db.Where("trace_id = ?", f.TraceID)
+	}
+	if f.ActionPrefix != "" {
+		db = db.Where(`action LIKE ? ESCAPE '\'`, escapeLike(f.ActionPrefix)+"%")
+	}
+	if !f.From.IsZero() {
+		db = db.Where("timestamp >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		db = db.Where("timestamp < ?", f.To)
+	}
+	return db
+}
+
+func orderClause(o repository.OrderBy) (string, error) {
+	if o.Field == "" {
+		return "timestamp DESC, id DESC", nil
+	}
+
+	field := strings.ToLower(o.Field)
+	if _, ok := sortable[field]; !ok {
+		return "", fmt.Errorf("auditgorm: unsupported order field %q", o.Field)
+	}
+
+	direction := strings.ToUpper(o.Direction)
+	switch direction {
+	case "":
+		direction = "ASC"
+	case "ASC", "DESC":
+	default:
+		return "", fmt.Errorf("auditgorm: unsupported order direction %q", o.Direction)
+	}
+
+	if field == "id" {
+		return "id " + direction, nil
+	}
+	return fmt.Sprintf("%s %s, id %s", field, direction, direction), nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
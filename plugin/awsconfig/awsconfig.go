@@ -0,0 +1,83 @@
+// Package awsconfig builds the aws.Config shared by the AWS plugins, so
+// clients can target LocalStack or ElasticMQ, another region or fixed
+// credentials.
+package awsconfig
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+type options struct {
+	config      *aws.Config
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+}
+
+// Option overrides part of the configuration built by Load.
+type Option func(*options)
+
+// WithConfig starts from cfg instead of the default configuration chain,
+// e.g. a config with assumed-role credentials. cfg itself is not modified.
+func WithConfig(cfg aws.Config) Option {
+	return func(o *options) {
+		o.config = &cfg
+	}
+}
+
+// WithEndpoint overrides the service endpoint, e.g. http://localhost:4566
+// for LocalStack.
+func WithEndpoint(url string) Option {
+	return func(o *options) {
+		o.endpoint = url
+	}
+}
+
+// WithRegion overrides the region of the configuration.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithStaticCredentials uses fixed credentials instead of the default chain.
+func WithStaticCredentials(accessKeyID, secretAccessKey, sessionToken string) Option {
+	return func(o *options) {
+		o.credentials = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken)
+	}
+}
+
+// Load returns the configuration given by WithConfig, or loaded from the
+// environment, with the other options applied on top.
+func Load(ctx context.Context, opts ...Option) (aws.Config, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var cfg aws.Config
+	if o.config != nil {
+		cfg = o.config.Copy()
+	} else {
+		var err error
+		if cfg, err = config.LoadDefaultConfig(ctx); err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	if o.region != "" {
+		cfg.Region = o.region
+	}
+	if o.credentials != nil {
+		cfg.Credentials = o.credentials
+	}
+	if o.endpoint != "" {
+		cfg.BaseEndpoint = aws.String(o.endpoint)
+	}
+
+	return cfg, nil
+}
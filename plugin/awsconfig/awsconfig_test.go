@@ -0,0 +1,34 @@
+package awsconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestLoad_AppliesOverrides(t *testing.T) {
+	ctx := context.Background()
+	base := aws.Config{Region: "us-east-1"}
+
+	cfg, err := Load(ctx,
+		WithConfig(base),
+		WithRegion("eu-west-1"),
+		WithEndpoint("http://localhost:4566"),
+		WithStaticCredentials("key", "secret", ""),
+	)
+	if err != nil {
+		t.Fatalf("unexpected config error: %v", err)
+	}
+	if cfg.Region != "eu-west-1" || aws.ToString(cfg.BaseEndpoint) != "http://localhost:4566" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil || creds.AccessKeyID != "key" || creds.SecretAccessKey != "secret" {
+		t.Fatalf("unexpected credentials %+v, %v", creds, err)
+	}
+	if base.Region != "us-east-1" {
+		t.Fatal("expected the given aws.Config to be left untouched")
+	}
+}
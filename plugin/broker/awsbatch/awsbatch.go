@@ -0,0 +1,111 @@
+// Package awsbatch holds what the SQS and SNS publishers share: encoding
+// payloads into message bodies and attributes, FIFO group and deduplication
+// IDs, splitting entries within the batch limits and retrying the entries
+// that failed.
+package awsbatch
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	// GroupIDAttribute sets the message group of a FIFO queue or topic.
+	GroupIDAttribute = "X-Message-Group-ID"
+	// DeduplicationIDAttribute overrides the content-based deduplication ID.
+	DeduplicationIDAttribute = "X-Message-Deduplication-ID"
+	// ContentEncodingBase64 marks bodies EncodeBody base64-encoded.
+	ContentEncodingBase64 = "base64"
+)
+
+// AttributeValue is a message attribute in the shape SQS and SNS share.
+type AttributeValue struct {
+	DataType    string
+	StringValue *string
+	BinaryValue []byte
+}
+
+// EncodeBody returns payload as a message body. Bodies must be valid UTF-8,
+// so binary payloads are base64-encoded and ContentEncodingBase64 is
+// returned.
+func EncodeBody(payload []byte) (string, string) {
+	if utf8.Valid(payload) {
+		return string(payload), ""
+	}
+	return base64.StdEncoding.EncodeToString(payload), ContentEncodingBase64
+}
+
+// BodySize returns the length of the body EncodeBody makes of payload.
+func BodySize(payload []byte) int {
+	if utf8.Valid(payload) {
+		return len(payload)
+	}
+	return base64.StdEncoding.EncodedLen(len(payload))
+}
+
+// IsFIFO reports whether the queue URL or topic ARN names a FIFO destination.
+func IsFIFO(destination string) bool {
+	return strings.HasSuffix(destination, ".fifo")
+}
+
+// FIFO returns the group and deduplication IDs of a message sent to
+// destination, nil when unset. FIFO destinations default to content-based
+// deduplication on payload, which must be the encoded payload rather than a
+// claim-check key. They also require a group ID, which is left to the
+// caller to check.
+func FIFO(destination string, attributes broker.Attributes, payload []byte) (group, dedup *string) {
+	if v, ok := attributes.Lookup(GroupIDAttribute); ok {
+		group = aws.String(v)
+	}
+	if v, ok := attributes.Lookup(DeduplicationIDAttribute); ok {
+		dedup = aws.String(v)
+	}
+
+	if dedup == nil && IsFIFO(destination) {
+		sum := sha256.Sum256(payload)
+		dedup = aws.String(hex.EncodeToString(sum[:]))
+	}
+	return group, dedup
+}
+
+// AttributeValueOf encodes values as the given type. Binary values are
+// stored base64-encoded and String.Array values are sent as a JSON array.
+func AttributeValueOf(t broker.AttributeType, values []string) (AttributeValue, error) {
+	switch t {
+	case broker.AttributeTypeNumber:
+		return AttributeValue{
+			DataType:    string(t),
+			StringValue: aws.String(values[0]),
+		}, nil
+	case broker.AttributeTypeBinary:
+		data, err := base64.StdEncoding.DecodeString(values[0])
+		if err != nil {
+			return AttributeValue{}, err
+		}
+		return AttributeValue{
+			DataType:    string(t),
+			BinaryValue: data,
+		}, nil
+	case broker.AttributeTypeStringArray:
+		data, err := json.Marshal(values)
+		if err != nil {
+			return AttributeValue{}, err
+		}
+		return AttributeValue{
+			DataType:    string(t),
+			StringValue: aws.String(string(data)),
+		}, nil
+	default:
+		return AttributeValue{
+			DataType:    string(broker.AttributeTypeString),
+			StringValue: aws.String(values[0]),
+		}, nil
+	}
+}
@@ -0,0 +1,134 @@
+package awsbatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type attributes map[string][]string
+
+func (a attributes) Add(key, value string) { a[key] = append(a[key], value) }
+
+func (a attributes) Get(key string) string {
+	if values := a[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (a attributes) Lookup(key string) (string, bool) {
+	values, ok := a[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (a attributes) Delete(key string) { delete(a, key) }
+
+func (a attributes) Values() map[string][]string { return a }
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{base: time.Second, attempt: 1, want: time.Second},
+		{base: time.Second, attempt: 3, want: 4 * time.Second},
+		{base: time.Second, attempt: 10, want: MaxBackoff},
+		{base: time.Second, attempt: 80, want: MaxBackoff},
+		{base: time.Minute, attempt: 5, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.base, tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%s, %d): expected %s, got %s", tt.base, tt.attempt, tt.want, got)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	entries := make([]int, 23)
+	batches := Split(entries, func(int) int { return 1 })
+	if len(batches) != 3 || len(batches[0]) != MaxEntries || len(batches[2]) != 3 {
+		t.Fatalf("expected batches of 10, 10 and 3, got %d batches", len(batches))
+	}
+
+	batches = Split(make([]int, 3), func(int) int { return 100 * 1024 })
+	if len(batches) != 2 || len(batches[0]) != 2 {
+		t.Fatalf("expected batches split by size, got %d batches", len(batches))
+	}
+}
+
+func TestFIFO(t *testing.T) {
+	attr := attributes{}
+	if group, dedup := FIFO("https://sqs/orders", attr, []byte("payload")); group != nil || dedup != nil {
+		t.Fatal("expected no FIFO IDs for a standard queue")
+	}
+
+	group, dedup := FIFO("https://sqs/orders.fifo", attr, []byte("payload"))
+	if group != nil || dedup == nil {
+		t.Fatal("expected a content-based deduplication id and no group")
+	}
+	if _, again := FIFO("https://sqs/orders.fifo", attr, []byte("payload")); aws.ToString(again) != aws.ToString(dedup) {
+		t.Fatal("expected equal payloads to share a deduplication id")
+	}
+
+	attr.Add(GroupIDAttribute, "customer-1")
+	attr.Add(DeduplicationIDAttribute, "dedup-1")
+	group, dedup = FIFO("https://sqs/orders.fifo", attr, []byte("payload"))
+	if aws.ToString(group) != "customer-1" || aws.ToString(dedup) != "dedup-1" {
+		t.Fatalf("unexpected ids %q and %q", aws.ToString(group), aws.ToString(dedup))
+	}
+}
+
+func TestSendAndRetry(t *testing.T) {
+	messages := make([]broker.Message, 3)
+	result := broker.NewPublishResult(messages)
+	entries := []*string{EntryID(0), EntryID(1), EntryID(2)}
+
+	rejected := errors.New("rejected")
+	calls := 0
+	call := func(_ context.Context, batch []*string) (Response, error) {
+		calls++
+		var res Response
+		for _, id := range batch {
+			switch aws.ToString(id) {
+			case "1":
+				res.Failed = append(res.Failed, Failure{ID: "1", Err: rejected, SenderFault: true})
+			case "2":
+				if calls == 1 {
+					res.Failed = append(res.Failed, Failure{ID: "2", Err: errors.New("throttled")})
+					continue
+				}
+				fallthrough
+			default:
+				res.Successful = append(res.Successful, Success{ID: aws.ToString(id), MessageID: "msg-" + aws.ToString(id)})
+			}
+		}
+		return res, nil
+	}
+
+	id := func(entry *string) *string { return entry }
+	Retry(context.Background(), entries, 3, time.Millisecond, func(ctx context.Context, entries []*string) []*string {
+		return Send(ctx, Split(entries, func(*string) int { return 1 }), id, call, result)
+	})
+
+	if calls != 2 {
+		t.Fatalf("expected the throttled entry to be retried once, got %d calls", calls)
+	}
+	for i, want := range []string{"msg-0", "", "msg-2"} {
+		if got := result.Entries[i].MessageID; got != want {
+			t.Errorf("entry %d: expected message id %q, got %q", i, want, got)
+		}
+	}
+	if !errors.Is(result.Entries[1].Err, rejected) {
+		t.Fatalf("expected the sender fault to be reported, got %v", result.Entries[1].Err)
+	}
+}
@@ -0,0 +1,165 @@
+package awsbatch
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+const (
+	// MaxEntries is the most entries SQS and SNS accept per batch request.
+	MaxEntries = 10
+	// MaxBytes is the SQS and SNS limit on the total size of a batch.
+	MaxBytes = 256 * 1024
+)
+
+// Backoff defaults for publisher retries.
+const (
+	DefaultBackoff = 100 * time.Millisecond
+	MaxBackoff     = 20 * time.Second
+)
+
+// Response is the outcome of one batch request.
+type Response struct {
+	Successful []Success
+	Failed     []Failure
+}
+
+// Success is an entry the service accepted.
+type Success struct {
+	ID        string
+	MessageID string
+}
+
+// Failure is an entry the service rejected. SenderFault reports that the
+// entry itself is invalid and retrying it will not help.
+type Failure struct {
+	ID          string
+	Err         error
+	SenderFault bool
+}
+
+// EntryID returns the Id of the entry of the i-th message, so batch results
+// can be traced back to it.
+func EntryID(i int) *string {
+	return aws.String(strconv.Itoa(i))
+}
+
+// Split splits entries into batches of at most MaxEntries entries and
+// MaxBytes bytes, as measured by size.
+func Split[E any](entries []E, size func(E) int) [][]E {
+	var batches [][]E
+	var total int
+	for _, entry := range entries {
+		n := size(entry)
+		if len(batches) == 0 || len(batches[len(batches)-1]) == MaxEntries || total+n > MaxBytes {
+			batches = append(batches, nil)
+			total = 0
+		}
+
+		batches[len(batches)-1] = append(batches[len(batches)-1], entry)
+		total += n
+	}
+
+	return batches
+}
+
+// Send sends each batch with call and records the outcome of its entries in
+// result, at the index encoded in their EntryID. It returns the failed
+// entries worth retrying: those of requests that failed as a whole with a
+// retryable error, and those rejected without a sender fault.
+func Send[E any](
+	ctx context.Context,
+	batches [][]E,
+	id func(E) *string,
+	call func(context.Context, []E) (Response, error),
+	result *broker.PublishResult,
+) []E {
+	var pending []E
+	for _, batch := range batches {
+		res, err := call(ctx, batch)
+		if err != nil {
+			for _, entry := range batch {
+				result.Entries[entryIndex(aws.ToString(id(entry)))].Err = err
+			}
+			if Retryable(err) {
+				pending = append(pending, batch...)
+			}
+			continue
+		}
+
+		for _, r := range res.Successful {
+			e := &result.Entries[entryIndex(r.ID)]
+			e.MessageID, e.Err = r.MessageID, nil
+		}
+
+		for _, r := range res.Failed {
+			result.Entries[entryIndex(r.ID)].Err = r.Err
+			if r.SenderFault {
+				continue
+			}
+			for _, entry := range batch {
+				if aws.ToString(id(entry)) == r.ID {
+					pending = append(pending, entry)
+					break
+				}
+			}
+		}
+	}
+
+	return pending
+}
+
+// Retry calls send with entries, then with the entries it returns, until
+// none are left, maxAttempts calls were made or ctx is done. It waits
+// Backoff(backoff, attempt) before each retry.
+func Retry[E any](ctx context.Context, entries []E, maxAttempts int, backoff time.Duration, send func(context.Context, []E) []E) {
+	for attempt := 1; ; attempt++ {
+		entries = send(ctx, entries)
+		if len(entries) == 0 || attempt >= maxAttempts {
+			return
+		}
+
+		if err := sleep(ctx, Backoff(backoff, attempt)); err != nil {
+			return
+		}
+	}
+}
+
+// Backoff returns how long to wait after the given attempt: base doubled
+// after each earlier attempt, capped at MaxBackoff or base, whichever is
+// larger.
+func Backoff(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < MaxBackoff; i++ {
+		d *= 2
+	}
+	return max(min(d, MaxBackoff), base)
+}
+
+// Retryable reports whether a failed request may succeed when sent again,
+// such as on throttling, server errors and connection failures.
+func Retryable(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+func entryIndex(id string) int {
+	i, _ := strconv.Atoi(id)
+	return i
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+// Package msgpack provides a MessagePack broker.Codec.
+package msgpack
+
+import (
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the content type stamped on MessagePack payloads.
+const ContentType = "application/msgpack"
+
+type codec struct{}
+
+var _ broker.DocumentCodec = codec{}
+
+// NewCodec returns a lightweight binary codec using MessagePack.
+func NewCodec() broker.Codec { return codec{} }
+
+func (codec) ContentType() string                { return ContentType }
+func (codec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (codec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+// UnmarshalDocument decodes data as a document so older payloads can be
+// upcast. Integers keep their exact value.
+func (codec) UnmarshalDocument(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if err := msgpack.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
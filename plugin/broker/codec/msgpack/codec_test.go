@@ -0,0 +1,54 @@
+package msgpack_test
+
+import (
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/codec/msgpack"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	ID    string
+	Total int
+}
+
+func TestCodecRoundTripThroughRegistry(t *testing.T) {
+	registry := broker.NewRegistry(msgpack.NewCodec(), broker.Gzip(msgpack.NewCodec()))
+
+	for _, contentType := range []string{msgpack.ContentType, msgpack.ContentType + "+gzip"} {
+		codec, ok := registry.Lookup(contentType)
+		require.True(t, ok, contentType)
+
+		data, err := codec.Marshal(order{ID: "o-1", Total: 42})
+		require.NoError(t, err)
+
+		attrs := inmem.NewMessage(nil, "orders").Attributes()
+		attrs.Add(broker.ContentTypeAttribute, contentType)
+
+		decoded, err := registry.DecoderTarget(order{})(attrs, data)
+		require.NoError(t, err)
+		assert.Equal(t, &order{ID: "o-1", Total: 42}, decoded)
+	}
+}
+
+func TestCodecUpcast(t *testing.T) {
+	registry := broker.NewRegistry(msgpack.NewCodec())
+	upcaster := broker.NewUpcaster(2).Register(1, func(doc map[string]any) (map[string]any, error) {
+		doc["Total"] = doc["Amount"]
+		delete(doc, "Amount")
+		return doc, nil
+	})
+
+	data, err := msgpack.NewCodec().Marshal(map[string]any{"ID": "o-1", "Amount": 42})
+	require.NoError(t, err)
+
+	attrs := inmem.NewMessage(nil, "orders").Attributes()
+	attrs.Add(broker.ContentTypeAttribute, msgpack.ContentType)
+
+	decoded, err := registry.DecoderTarget(order{}, broker.WithUpcaster(upcaster))(attrs, data)
+	require.NoError(t, err)
+	assert.Equal(t, &order{ID: "o-1", Total: 42}, decoded)
+}
@@ -0,0 +1,41 @@
+// Package protobuf provides a Protocol Buffers broker.Codec.
+package protobuf
+
+import (
+	"errors"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the content type stamped on protobuf payloads.
+const ContentType = "application/x-protobuf"
+
+// ErrNotProtoMessage is returned when a payload or target is not a proto.Message.
+var ErrNotProtoMessage = errors.New("value does not implement proto.Message")
+
+type codec struct{}
+
+var _ broker.Codec = codec{}
+
+// NewCodec returns a codec for generated protobuf messages. Payloads and
+// decode targets must be pointers to generated types.
+func NewCodec() broker.Codec { return codec{} }
+
+func (codec) ContentType() string { return ContentType }
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
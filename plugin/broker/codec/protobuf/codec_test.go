@@ -0,0 +1,33 @@
+package protobuf_test
+
+import (
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/codec/protobuf"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	registry := broker.NewRegistry(protobuf.NewCodec())
+	codec, ok := registry.Lookup(protobuf.ContentType)
+	require.True(t, ok)
+
+	data, err := codec.Marshal(wrapperspb.String("o-1"))
+	require.NoError(t, err)
+
+	attrs := inmem.NewMessage(nil, "orders").Attributes()
+	attrs.Add(broker.ContentTypeAttribute, protobuf.ContentType)
+
+	decoded, err := registry.DecoderTarget(wrapperspb.StringValue{})(attrs, data)
+	require.NoError(t, err)
+	assert.Equal(t, "o-1", decoded.(*wrapperspb.StringValue).GetValue())
+}
+
+func TestCodecRejectsNonProtoValues(t *testing.T) {
+	_, err := protobuf.NewCodec().Marshal(struct{ ID string }{ID: "o-1"})
+	assert.ErrorIs(t, err, protobuf.ErrNotProtoMessage)
+}
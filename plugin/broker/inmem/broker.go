@@ -0,0 +1,276 @@
+// Package inmem provides an in-memory broker.Publisher and broker.Subscriber
+// with SQS-like semantics for tests and local development.
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoDestination = errors.New("message has no queue or topic attribute")
+	ErrClosed        = errors.New("subscriber closed")
+	// ErrInvalidReceipt is returned by Commit when the message is no longer
+	// in flight under that receipt, e.g. its visibility timeout expired and
+	// it was received again.
+	ErrInvalidReceipt = errors.New("receipt handle is invalid or expired")
+)
+
+const defaultVisibilityTimeout = 30 * time.Second
+
+type envelope struct {
+	id           string
+	body         []byte
+	attrs        map[string][]string
+	visibleAt    time.Time
+	receipt      string
+	receiveCount int
+}
+
+type queue struct {
+	messages []*envelope
+}
+
+// Broker holds named queues and topics. A topic fans every message out to
+// the queues bound to it; any other destination is a queue, created on first use.
+type Broker struct {
+	mu                sync.Mutex
+	queues            map[string]*queue
+	topics            map[string][]string
+	encoder           broker.Encoder
+	visibilityTimeout time.Duration
+	offset            time.Duration
+	notify            chan struct{}
+}
+
+var _ broker.Publisher = (*Broker)(nil)
+
+type Option func(*Broker)
+
+// WithEncoder sets how payloads are serialised on Publish. Defaults to JSON.
+func WithEncoder(encoder broker.Encoder) Option {
+	return func(b *Broker) {
+		b.encoder = encoder
+	}
+}
+
+// WithVisibilityTimeout sets how long a received message stays hidden before
+// it reappears if it is not committed. Defaults to 30 seconds.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(b *Broker) {
+		b.visibilityTimeout = timeout
+	}
+}
+
+func New(opts ...Option) *Broker {
+	b := &Broker{
+		queues: make(map[string]*queue),
+		topics: make(map[string][]string),
+		encoder: func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		},
+		visibilityTimeout: defaultVisibilityTimeout,
+		notify:            make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Bind subscribes queue to topic so every message published to topic is
+// copied into queue.
+func (b *Broker) Bind(topic, queue string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !slices.Contains(b.topics[topic], queue) {
+		b.topics[topic] = append(b.topics[topic], queue)
+	}
+	b.queue(queue)
+}
+
+// Publish implements broker.Publisher. Messages are encoded up front so a
+// failing encoder publishes nothing.
+func (b *Broker) Publish(ctx context.Context, messages ...broker.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	type pending struct {
+		destination string
+		delay       time.Duration
+		envelope    envelope
+	}
+
+	batch := make([]pending, 0, len(messages))
+	for _, m := range messages {
+		destination, ok := m.Attributes().Lookup(MessageQueue)
+		if !ok {
+			destination, ok = m.Attributes().Lookup(MessageTopic)
+		}
+		if !ok || destination == "" {
+			return ErrNoDestination
+		}
+
+		body, err := b.encoder(m)
+		if err != nil {
+			return err
+		}
+
+		var delay time.Duration
+		if value, ok := m.Attributes().Lookup(MessageDelaySecond); ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s attribute: %w", MessageDelaySecond, err)
+			}
+			delay = time.Duration(seconds) * time.Second
+		}
+
+		batch = append(batch, pending{
+			destination: destination,
+			delay:       delay,
+			envelope: envelope{
+				body:  body,
+				attrs: m.Attributes().Values(),
+			},
+		})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range batch {
+		targets := []string{p.destination}
+		if bound, ok := b.topics[p.destination]; ok {
+			targets = bound
+		}
+
+		for _, name := range targets {
+			e := p.envelope
+			e.id = uuid.NewString()
+			e.attrs = cloneValues(p.envelope.attrs)
+			e.visibleAt = b.now().Add(p.delay)
+			b.queue(name).messages = append(b.queue(name).messages, &e)
+		}
+	}
+
+	b.wake()
+	return nil
+}
+
+// NewSubscriber returns a Subscriber receiving from queue.
+func (b *Broker) NewSubscriber(queue string, opts ...SubscriberOption) *Subscriber {
+	b.mu.Lock()
+	b.queue(queue)
+	b.mu.Unlock()
+
+	return newSubscriber(b, queue, opts...)
+}
+
+// Advance moves the broker clock forward, releasing delayed messages and
+// expiring visibility timeouts without sleeping.
+func (b *Broker) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.offset += d
+	b.wake()
+}
+
+// Len returns the number of messages in queue that were not committed yet,
+// including delayed and in-flight ones.
+func (b *Broker) Len(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q, ok := b.queues[queue]; ok {
+		return len(q.messages)
+	}
+	return 0
+}
+
+func (b *Broker) now() time.Time {
+	return time.Now().Add(b.offset)
+}
+
+// queue returns the named queue, creating it. Callers hold b.mu.
+func (b *Broker) queue(name string) *queue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// wake releases every Subscribe waiting for new messages. Callers hold b.mu.
+func (b *Broker) wake() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// receive hides and returns the first visible message in queue. When none is
+// visible it returns a channel closed on the next change and how long until
+// the next message becomes visible (zero when there is none).
+func (b *Broker) receive(name string) (*envelope, <-chan struct{}, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	var next time.Duration
+	for _, e := range b.queue(name).messages {
+		if !e.visibleAt.After(now) {
+			e.visibleAt = now.Add(b.visibilityTimeout)
+			e.receipt = uuid.NewString()
+			e.receiveCount++
+
+			received := *e
+			received.attrs = cloneValues(e.attrs)
+			return &received, nil, 0
+		}
+		if wait := e.visibleAt.Sub(now); next == 0 || wait < next {
+			next = wait
+		}
+	}
+
+	return nil, b.notify, next
+}
+
+func (b *Broker) commit(name, receipt string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(name)
+	for i, e := range q.messages {
+		if e.receipt == receipt && receipt != "" {
+			q.messages = slices.Delete(q.messages, i, i+1)
+			return nil
+		}
+	}
+	return ErrInvalidReceipt
+}
+
+func (b *Broker) extend(name, receipt string, d time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range b.queue(name).messages {
+		if e.receipt == receipt && receipt != "" {
+			e.visibleAt = b.now().Add(d)
+			return nil
+		}
+	}
+	return ErrInvalidReceipt
+}
@@ -0,0 +1,189 @@
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/subscriber"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func subscribe(t *testing.T, sub *Subscriber) (order, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	return *msg.Payload().(*order), msg.Attributes().Get(MessageReceiptHandle)
+}
+
+func expectEmpty(t *testing.T, sub *Subscriber) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := sub.Subscribe(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected no visible message, got %v", err)
+	}
+}
+
+func TestBroker_VisibilityTimeout(t *testing.T) {
+	b := New(WithVisibilityTimeout(time.Minute))
+	sub := b.NewSubscriber("orders", WithDecoderTarget(order{}))
+	ctx := context.Background()
+
+	if err := b.Publish(ctx, NewMessage(order{ID: "o-1"}, "orders")); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	got, staleReceipt := subscribe(t, sub)
+	if got.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	expectEmpty(t, sub)
+
+	b.Advance(time.Minute)
+
+	got, receipt := subscribe(t, sub)
+	if got.ID != "o-1" {
+		t.Fatalf("expected uncommitted message to reappear, got %+v", got)
+	}
+
+	stale := NewMessage(nil, "orders")
+	stale.Attributes().Add(MessageReceiptHandle, staleReceipt)
+	if err := sub.Commit(ctx, stale); !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("expected ErrInvalidReceipt for stale receipt, got %v", err)
+	}
+
+	current := NewMessage(nil, "orders")
+	current.Attributes().Add(MessageReceiptHandle, receipt)
+	if err := sub.Commit(ctx, current); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if b.Len("orders") != 0 {
+		t.Fatalf("expected queue to be empty after commit, got %d", b.Len("orders"))
+	}
+}
+
+func TestBroker_TopicFanOutAndDelay(t *testing.T) {
+	b := New()
+	b.Bind("order-events", "billing")
+	b.Bind("order-events", "shipping")
+	billing := b.NewSubscriber("billing", WithDecoderTarget(order{}))
+	shipping := b.NewSubscriber("shipping", WithDecoderTarget(order{}))
+
+	err := b.Publish(context.Background(), NewMessage(order{ID: "o-2"}, "order-events", WithDelaySecond(10)))
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	expectEmpty(t, billing)
+	b.Advance(10 * time.Second)
+
+	for _, sub := range []*Subscriber{billing, shipping} {
+		if got, _ := subscribe(t, sub); got.ID != "o-2" {
+			t.Fatalf("unexpected payload %+v", got)
+		}
+	}
+}
+
+func TestBroker_HandlerPipeline(t *testing.T) {
+	b := New()
+	sub := b.NewSubscriber("orders", WithDecoderTarget(order{}))
+
+	received := make(chan order, 2)
+	handler := subscriber.NewHandler(sub, func(_ context.Context, o order) error {
+		received <- o
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := handler.Start(ctx, 1); err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+
+	err := b.Publish(ctx, NewMessage(order{ID: "o-1"}, "orders"), NewMessage(order{ID: "o-2"}, "orders"))
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	for _, want := range []string{"o-1", "o-2"} {
+		select {
+		case got := <-received:
+			if got.ID != want {
+				t.Fatalf("expected %q, got %q", want, got.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for handler")
+		}
+	}
+
+	cancel()
+	sub.Close(context.Background())
+	handler.Stop()
+
+	if b.Len("orders") != 0 {
+		t.Fatalf("expected handler to commit every message, %d left", b.Len("orders"))
+	}
+}
+
+func TestBroker_CloseUnblocksSubscribe(t *testing.T) {
+	b := New()
+	sub := b.NewSubscriber("orders")
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sub.Subscribe(context.Background())
+		errCh <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	sub.Close(context.Background())
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscribe did not return after close")
+	}
+}
+
+func TestBroker_ContentTypeMigration(t *testing.T) {
+	registry := broker.NewRegistry()
+	gzipEncoder := registry.Encoder(broker.ContentTypeJSON + "+gzip")
+
+	// o-1 comes from a legacy producer publishing plain JSON without a
+	// content type, o-2 from one migrated to gzipped JSON.
+	b := New(WithEncoder(func(m broker.Message) ([]byte, error) {
+		if m.Payload().(order).ID == "o-1" {
+			return json.Marshal(m.Payload())
+		}
+		return gzipEncoder(m)
+	}))
+	sub := b.NewSubscriber("orders", WithContentDecoder(registry.DecoderTarget(order{})))
+
+	err := b.Publish(context.Background(),
+		NewMessage(order{ID: "o-1"}, "orders"), NewMessage(order{ID: "o-2"}, "orders"))
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	for _, want := range []string{"o-1", "o-2"} {
+		if got, _ := subscribe(t, sub); got.ID != want {
+			t.Fatalf("expected %q, got %q", want, got.ID)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package inmem
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// Attribute keys match the sqs and sns plugins so messages built with
+// sqs.NewMessage or sns.NewMessage can be published to the in-memory broker.
+const (
+	MessageID            = "X-Message-ID"
+	MessageReceiptHandle = "X-Message-Receipt-Handle"
+	MessageQueue         = "X-Message-Queue"
+	MessageTopic         = "topic"
+	MessageDelaySecond   = "X-Message-Delay-Second"
+	MessageReceiveCount  = "X-Message-Receive-Count"
+)
+
+type message struct {
+	data any
+	attr *attributes
+}
+
+type MessageOption func(*message)
+
+// WithDelaySecond hides the message from subscribers for delay seconds.
+func WithDelaySecond(delay int) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageDelaySecond, strconv.Itoa(delay))
+	}
+}
+
+func (m *message) Attributes() broker.Attributes { return m.attr }
+func (m *message) Payload() any                  { return m.data }
+
+// NewMessage addresses payload to a queue, or to a topic declared with Bind.
+func NewMessage(payload any, destination string, opts ...MessageOption) broker.Message {
+	m := &message{
+		data: payload,
+		attr: newAttributes(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.attr.Add(MessageQueue, destination)
+
+	return m
+}
+
+type attributes struct {
+	mu         sync.RWMutex
+	attributes map[string][]string
+}
+
+var _ broker.Attributes = (*attributes)(nil)
+
+func newAttributes() *attributes {
+	return &attributes{attributes: make(map[string][]string)}
+}
+
+func (a *attributes) Add(key, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attributes[key] = append(a.attributes[key], value)
+}
+
+func (a *attributes) Get(key string) string {
+	value, _ := a.Lookup(key)
+	return value
+}
+
+func (a *attributes) Lookup(key string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	values, ok := a.attributes[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (a *attributes) Delete(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.attributes, key)
+}
+
+func (a *attributes) Values() map[string][]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return cloneValues(a.attributes)
+}
+
+func cloneValues(values map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(values))
+	for key, v := range maps.All(values) {
+		out[key] = slices.Clone(v)
+	}
+	return out
+}
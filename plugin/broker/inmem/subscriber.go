@@ -0,0 +1,225 @@
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+type subscriberOptions struct {
+	decoder        broker.Decoder
+	contentDecoder broker.ContentDecoder
+}
+
+type SubscriberOption func(*subscriberOptions)
+
+func WithDecoder(decoder broker.Decoder) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.decoder = decoder
+	}
+}
+
+// WithContentDecoder decodes each payload according to its
+// broker.ContentTypeAttribute, e.g. with broker.Registry.DecoderTarget. It
+// takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.contentDecoder = decoder
+	}
+}
+
+// WithDecoderTarget decodes JSON payloads into a new *T, the shape expected
+// by subscriber.Handler[T].
+func WithDecoderTarget(typeof any) SubscriberOption {
+	return func(s *subscriberOptions) {
+		v := reflect.TypeOf(typeof)
+
+		s.decoder = func(b []byte) (any, error) {
+			target := reflect.New(v).Interface()
+			if err := json.Unmarshal(b, target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		}
+	}
+}
+
+type Subscriber struct {
+	broker  *Broker
+	queue   string
+	options *subscriberOptions
+	closed  atomic.Bool
+}
+
+var (
+	_ broker.BatchSubscriber = (*Subscriber)(nil)
+	_ broker.Extender        = (*Subscriber)(nil)
+)
+
+func newSubscriber(b *Broker, queue string, opts ...SubscriberOption) *Subscriber {
+	options := &subscriberOptions{
+		decoder: func(b []byte) (any, error) {
+			var target any
+			if err := json.Unmarshal(b, &target); err != nil {
+				return nil, err
+			}
+			return target, nil
+		},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Subscriber{broker: b, queue: queue, options: options}
+}
+
+// Subscribe implements broker.Subscriber. It blocks until a message is
+// visible, the subscriber is closed or ctx is done. The message stays hidden
+// for the visibility timeout and reappears unless it is committed.
+func (s *Subscriber) Subscribe(ctx context.Context) (broker.Message, error) {
+	for {
+		if s.closed.Load() {
+			return nil, ErrClosed
+		}
+
+		e, changed, next := s.broker.receive(s.queue)
+		if e != nil {
+			return s.toMessage(e)
+		}
+
+		if err := wait(ctx, changed, next); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SubscribeBatch implements broker.BatchSubscriber. It blocks like
+// Subscribe for the first message and then takes up to limit visible ones.
+func (s *Subscriber) SubscribeBatch(ctx context.Context, limit int) ([]broker.Message, error) {
+	limit = max(limit, 1)
+
+	var (
+		messages []broker.Message
+		errs     []error
+	)
+	for len(messages)+len(errs) < limit {
+		if s.closed.Load() {
+			return nil, ErrClosed
+		}
+
+		e, changed, next := s.broker.receive(s.queue)
+		if e == nil {
+			if len(messages)+len(errs) > 0 {
+				break
+			}
+			if err := wait(ctx, changed, next); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		message, err := s.toMessage(e)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, errors.Join(errs...)
+}
+
+// wait blocks until changed is closed, next elapses (when positive) or ctx is done.
+func wait(ctx context.Context, changed <-chan struct{}, next time.Duration) error {
+	var timeout <-chan time.Time
+	if next > 0 {
+		timer := time.NewTimer(next)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-changed:
+	case <-timeout:
+	}
+	return nil
+}
+
+// Commit implements broker.Subscriber by deleting the message from the queue.
+func (s *Subscriber) Commit(ctx context.Context, message broker.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.broker.commit(s.queue, message.Attributes().Get(MessageReceiptHandle))
+}
+
+// Extend implements broker.Extender by hiding the message for d from now.
+func (s *Subscriber) Extend(ctx context.Context, message broker.Message, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.broker.extend(s.queue, message.Attributes().Get(MessageReceiptHandle), d)
+}
+
+// CommitBatch implements broker.BatchSubscriber.
+func (s *Subscriber) CommitBatch(ctx context.Context, messages []broker.Message) error {
+	var errs []error
+	for _, message := range messages {
+		if err := s.Commit(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements broker.Subscriber. Blocked Subscribe calls return ErrClosed.
+func (s *Subscriber) Close(_ context.Context) {
+	s.closed.Store(true)
+
+	s.broker.mu.Lock()
+	s.broker.wake()
+	s.broker.mu.Unlock()
+}
+
+func (s *Subscriber) toMessage(e *envelope) (broker.Message, error) {
+	attr := newAttributes()
+	for key, values := range e.attrs {
+		for _, v := range values {
+			attr.Add(key, v)
+		}
+	}
+	attr.Delete(MessageID)
+	attr.Delete(MessageReceiptHandle)
+	attr.Delete(MessageReceiveCount)
+	attr.Delete(MessageQueue)
+	attr.Add(MessageID, e.id)
+	attr.Add(MessageReceiptHandle, e.receipt)
+	attr.Add(MessageReceiveCount, strconv.Itoa(e.receiveCount))
+	attr.Add(MessageQueue, s.queue)
+
+	payload, err := s.decode(attr, e.body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message{data: payload, attr: attr}, nil
+}
+
+func (s *Subscriber) decode(attr broker.Attributes, body []byte) (any, error) {
+	if s.options.contentDecoder != nil {
+		return s.options.contentDecoder(attr, body)
+	}
+	return s.options.decoder(body)
+}
@@ -0,0 +1,10 @@
+package kafka
+
+import "errors"
+
+var (
+	ErrNoTopic = errors.New("message has no topic attribute")
+	// ErrNoOffset is returned by Commit for messages that were not received
+	// from a Subscriber and therefore carry no partition and offset.
+	ErrNoOffset = errors.New("message has no partition or offset attribute")
+)
@@ -0,0 +1,308 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeTopic is an in-process stand-in for a two-partition topic:
+// the Writer side appends records and the Reader side hands them out in order
+// and records the committed offset of each partition.
+type fakeTopic struct {
+	mu        sync.Mutex
+	records   []kafka.Message
+	next      int
+	committed map[int]int64
+}
+
+func newFakeTopic() *fakeTopic {
+	return &fakeTopic{committed: make(map[int]int64)}
+}
+
+func (f *fakeTopic) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range msgs {
+		m.Partition = len(m.Key) % 2
+		m.Offset = int64(len(f.records))
+		f.records = append(f.records, m)
+	}
+	return nil
+}
+
+func (f *fakeTopic) FetchMessage(_ context.Context) (kafka.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.next >= len(f.records) {
+		return kafka.Message{}, context.DeadlineExceeded
+	}
+	m := f.records[f.next]
+	f.next++
+	return m, nil
+}
+
+func (f *fakeTopic) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range msgs {
+		if m.Offset+1 > f.committed[m.Partition] {
+			f.committed[m.Partition] = m.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (f *fakeTopic) Close() error { return nil }
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestPublishSubscribeCommit(t *testing.T) {
+	ctx := context.Background()
+	topic := newFakeTopic()
+	pub := NewPublisher(nil, WithWriter(topic))
+	sub := NewSubscriber(nil, "billing", "orders", WithReader(topic), WithDecoderTarget(order{}))
+
+	err := pub.Publish(ctx,
+		NewMessage(order{ID: "o-1"}, "orders", WithKey("customer-1"), WithHeader("X-Trace-ID", "trace-1")),
+		NewMessage(order{ID: "o-2"}, "orders"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	first := topic.records[0]
+	if first.Topic != "orders" || string(first.Key) != "customer-1" {
+		t.Fatalf("unexpected record routing: topic=%q key=%q", first.Topic, first.Key)
+	}
+	if len(first.Headers) != 1 || first.Headers[0].Key != "X-Trace-ID" {
+		t.Fatalf("expected only the trace header, got %+v", first.Headers)
+	}
+
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if got := msg.Payload().(*order); got.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	attr := msg.Attributes()
+	if attr.Get("X-Trace-ID") != "trace-1" || attr.Get(MessageKey) != "customer-1" {
+		t.Fatalf("headers not mapped back to attributes: %v", attr.Values())
+	}
+	if attr.Get(MessagePartition) != "0" || attr.Get(MessageOffset) != "0" {
+		t.Fatalf("unexpected partition/offset attributes: %v", attr.Values())
+	}
+
+	if err := sub.Commit(ctx, msg); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+
+	second, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if _, ok := second.Attributes().Lookup(MessageKey); ok {
+		t.Fatal("expected no key attribute for an unkeyed record")
+	}
+	if err := sub.Commit(ctx, second); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+
+	if topic.committed[0] != 2 {
+		t.Fatalf("expected partition 0 committed up to offset 2, got %v", topic.committed)
+	}
+}
+
+func TestPublishRequiresTopic(t *testing.T) {
+	topic := newFakeTopic()
+	pub := NewPublisher(nil, WithWriter(topic))
+
+	err := pub.Publish(context.Background(), NewMessage(order{ID: "o-1"}, ""))
+	if !errors.Is(err, ErrNoTopic) {
+		t.Fatalf("expected ErrNoTopic, got %v", err)
+	}
+	if len(topic.records) != 0 {
+		t.Fatalf("expected nothing to be written, got %d records", len(topic.records))
+	}
+}
+
+func TestCommitRequiresOffset(t *testing.T) {
+	sub := NewSubscriber(nil, "billing", "orders", WithReader(newFakeTopic()))
+
+	err := sub.Commit(context.Background(), NewMessage(order{ID: "o-1"}, "orders"))
+	if !errors.Is(err, ErrNoOffset) {
+		t.Fatalf("expected ErrNoOffset, got %v", err)
+	}
+}
+
+func TestCommitIsInOffsetOrder(t *testing.T) {
+	ctx := context.Background()
+	topic := newFakeTopic()
+	pub := NewPublisher(nil, WithWriter(topic))
+	sub := NewSubscriber(nil, "billing", "orders", WithReader(topic), WithDecoderTarget(order{}))
+
+	for _, id := range []string{"o-1", "o-2", "o-3"} {
+		if err := pub.Publish(ctx, NewMessage(order{ID: id}, "orders")); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	msgs := make([]broker.Message, 3)
+	for i := range msgs {
+		msg, err := sub.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("unexpected subscribe error: %v", err)
+		}
+		msgs[i] = msg
+	}
+
+	for _, i := range []int{2, 1} {
+		if err := sub.Commit(ctx, msgs[i]); err != nil {
+			t.Fatalf("unexpected commit error: %v", err)
+		}
+	}
+	if topic.committed[0] != 0 {
+		t.Fatalf("expected nothing committed while offset 0 is in flight, got %v", topic.committed)
+	}
+
+	if err := sub.Commit(ctx, msgs[0]); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if topic.committed[0] != 3 {
+		t.Fatalf("expected partition 0 committed up to offset 3, got %v", topic.committed)
+	}
+}
+
+func TestUndecodableRecords(t *testing.T) {
+	ctx := context.Background()
+
+	publish := func(topic *fakeTopic) {
+		if err := topic.WriteMessages(ctx,
+			kafka.Message{Topic: "orders", Value: []byte("{not json")},
+			kafka.Message{Topic: "orders", Value: []byte(`{"id":"o-2"}`)},
+		); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	t.Run("without dead letter", func(t *testing.T) {
+		topic := newFakeTopic()
+		publish(topic)
+		sub := NewSubscriber(nil, "billing", "orders", WithReader(topic), WithDecoderTarget(order{}))
+
+		if _, err := sub.Subscribe(ctx); err == nil {
+			t.Fatal("expected a decode error")
+		}
+		msg, err := sub.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("unexpected subscribe error: %v", err)
+		}
+		if err := sub.Commit(ctx, msg); err != nil {
+			t.Fatalf("unexpected commit error: %v", err)
+		}
+		if topic.committed[0] != 0 {
+			t.Fatalf("expected the undecodable record to hold back commits, got %v", topic.committed)
+		}
+	})
+
+	t.Run("with dead letter", func(t *testing.T) {
+		topic, dlq := newFakeTopic(), newFakeTopic()
+		publish(topic)
+		sub := NewSubscriber(nil, "billing", "orders",
+			WithReader(topic), WithDecoderTarget(order{}), WithDeadLetter(dlq, "orders.dlq"))
+
+		msg, err := sub.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("unexpected subscribe error: %v", err)
+		}
+		if got := msg.Payload().(*order); got.ID != "o-2" {
+			t.Fatalf("expected the undecodable record to be skipped, got %+v", got)
+		}
+
+		if len(dlq.records) != 1 {
+			t.Fatalf("expected one dead-lettered record, got %d", len(dlq.records))
+		}
+		dead := dlq.records[0]
+		if dead.Topic != "orders.dlq" || string(dead.Value) != "{not json" {
+			t.Fatalf("unexpected dead-lettered record %+v", dead)
+		}
+		if len(dead.Headers) != 1 || dead.Headers[0].Key != DeadLetterReason {
+			t.Fatalf("expected a %s header, got %+v", DeadLetterReason, dead.Headers)
+		}
+
+		if err := sub.Commit(ctx, msg); err != nil {
+			t.Fatalf("unexpected commit error: %v", err)
+		}
+		if topic.committed[0] != 2 {
+			t.Fatalf("expected partition 0 committed up to offset 2, got %v", topic.committed)
+		}
+	})
+}
+
+func TestCommitRecoversFromRebalance(t *testing.T) {
+	ctx := context.Background()
+	topic := newFakeTopic()
+	sub := NewSubscriber(nil, "billing", "orders",
+		WithReader(topic), WithDecoderTarget(order{}), WithMaxUncommitted(3))
+
+	for i := range 6 {
+		if err := topic.WriteMessages(ctx, kafka.Message{Topic: "orders", Value: []byte(`{"id":"o-` + strconv.Itoa(i) + `"}`)}); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	subscribe := func() broker.Message {
+		t.Helper()
+		msg, err := sub.Subscribe(ctx)
+		if err != nil {
+			t.Fatalf("unexpected subscribe error: %v", err)
+		}
+		return msg
+	}
+	commit := func(msg broker.Message) {
+		t.Helper()
+		if err := sub.Commit(ctx, msg); err != nil {
+			t.Fatalf("unexpected commit error: %v", err)
+		}
+	}
+
+	// Offset 0 is never committed, holding back 1 and 2.
+	subscribe()
+	commit(subscribe())
+	commit(subscribe())
+	if topic.committed[0] != 0 {
+		t.Fatalf("expected nothing committed past the uncommitted record, got %v", topic.committed)
+	}
+
+	// The partition is revoked and comes back once another member has
+	// committed up to offset 4.
+	topic.mu.Lock()
+	topic.next, topic.committed[0] = 4, 4
+	topic.mu.Unlock()
+
+	commit(subscribe())
+	if topic.committed[0] != 5 {
+		t.Fatalf("expected commits to resume after the rebalance, got %v", topic.committed)
+	}
+
+	// The partition is read again from an earlier offset.
+	topic.mu.Lock()
+	topic.next = 1
+	topic.mu.Unlock()
+
+	commit(subscribe())
+	if tracked := sub.pending[partition{topic: "orders", id: 0}]; len(tracked.fetched) != 0 || len(tracked.done) != 0 {
+		t.Fatalf("expected stale offsets to be dropped, tracking %v", tracked)
+	}
+}
@@ -0,0 +1,64 @@
+// Package kafka implements broker.Publisher and broker.Subscriber on top of
+// Kafka consumer groups.
+package kafka
+
+import (
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// Attribute keys with a Kafka meaning. Every other attribute is sent as a
+// record header.
+const (
+	MessageTopic     = "X-Message-Topic"
+	MessageKey       = "X-Message-Key"
+	MessagePartition = "X-Message-Partition"
+	MessageOffset    = "X-Message-Offset"
+)
+
+// DeadLetterReason is the header holding the decode error of a record sent
+// to the WithDeadLetter topic.
+const DeadLetterReason = "X-Dead-Letter-Reason"
+
+type message struct {
+	data      any
+	attr      *attributes
+	createdAt time.Time
+}
+
+type MessageOption func(*message)
+
+// WithKey sets the record key, which selects the partition and therefore
+// the ordering scope of the message.
+func WithKey(key string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageKey, key)
+	}
+}
+
+// WithHeader adds a record header.
+func WithHeader(key, value string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(key, value)
+	}
+}
+
+func (m *message) Attributes() broker.Attributes { return m.attr }
+func (m *message) Payload() any                  { return m.data }
+
+func NewMessage(payload any, topic string, opts ...MessageOption) broker.Message {
+	message := &message{
+		data:      payload,
+		attr:      newAttributes(),
+		createdAt: time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(message)
+	}
+
+	message.attr.Add(MessageTopic, topic)
+
+	return message
+}
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer is the subset of *kafka.Writer used by Publisher.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type Publisher struct {
+	options *publisherOption
+	writer  Writer
+}
+
+var _ broker.Publisher = (*Publisher)(nil)
+
+// Publish implements broker.Publisher. Messages are routed by their topic
+// attribute and partitioned by their key attribute, if any.
+func (p *Publisher) Publish(ctx context.Context, messages ...broker.Message) error {
+	records, err := mapToRecords(p.options.encoder, messages...)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, records...)
+}
+
+// Close flushes pending writes and releases the writer.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
+
+// NewPublisher returns a Publisher writing to brokers. Records are hashed
+// to partitions by key, so messages sharing a key keep their order.
+func NewPublisher(brokers []string, opts ...PublisherOption) *Publisher {
+	options := newPublisherOption(opts...)
+
+	if options.writer == nil {
+		options.writer = &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Balancer: &kafka.Hash{},
+		}
+	}
+
+	return &Publisher{
+		options: options,
+		writer:  options.writer,
+	}
+}
+
+func mapToRecords(encoder broker.Encoder, messages ...broker.Message) ([]kafka.Message, error) {
+	records := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		topic, ok := m.Attributes().Lookup(MessageTopic)
+		if !ok || topic == "" {
+			return nil, ErrNoTopic
+		}
+
+		value, err := encoder(m)
+		if err != nil {
+			return nil, err
+		}
+
+		record := kafka.Message{Topic: topic, Value: value}
+		if key, ok := m.Attributes().Lookup(MessageKey); ok {
+			record.Key = []byte(key)
+		}
+
+		for key, values := range m.Attributes().Values() {
+			if isReserved(key) {
+				continue
+			}
+			for _, v := range values {
+				record.Headers = append(record.Headers, kafka.Header{Key: key, Value: []byte(v)})
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func isReserved(key string) bool {
+	switch key {
+	case MessageTopic, MessageKey, MessagePartition, MessageOffset:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"encoding/json"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+type publisherOption struct {
+	encoder broker.Encoder
+	writer  Writer
+}
+
+type PublisherOption func(*publisherOption)
+
+func WithEncoder(encoder broker.Encoder) PublisherOption {
+	return func(po *publisherOption) {
+		po.encoder = encoder
+	}
+}
+
+// WithWriter replaces the kafka.Writer built by NewPublisher, e.g. to tune
+// batching or use a fake in tests. The writer must not set a Topic, since
+// each message carries its own.
+func WithWriter(writer Writer) PublisherOption {
+	return func(po *publisherOption) {
+		po.writer = writer
+	}
+}
+
+func newPublisherOption(opts ...PublisherOption) *publisherOption {
+	options := &publisherOption{
+		encoder: func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
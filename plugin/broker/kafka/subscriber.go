@@ -0,0 +1,282 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// Reader is the subset of *kafka.Reader used by Subscriber.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type Subscriber struct {
+	options *subscriberOptions
+	reader  Reader
+
+	fetchMu sync.Mutex
+	mu      sync.Mutex
+	pending map[partition]*offsets
+}
+
+var _ broker.Subscriber = (*Subscriber)(nil)
+
+type partition struct {
+	topic string
+	id    int
+}
+
+// offsets tracks the fetched offsets of a partition that are not committed
+// yet, in ascending order, which of them have been processed, and the last
+// offset fetched.
+type offsets struct {
+	fetched []int64
+	done    map[int64]struct{}
+	last    int64
+}
+
+// Subscribe implements broker.Subscriber. It blocks until a record is
+// fetched and exposes its headers, key, topic, partition and offset as
+// attributes. Records that fail to decode are sent to the WithDeadLetter
+// topic and skipped; without one the error is returned and the record is
+// never committed.
+func (s *Subscriber) Subscribe(ctx context.Context) (broker.Message, error) {
+	for {
+		record, err := s.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := mapRecordToMessage(s.options, record)
+		if err == nil {
+			return msg, nil
+		}
+		if s.options.deadLetter == nil {
+			return nil, err
+		}
+		if dlqErr := s.deadLetter(ctx, record, err); dlqErr != nil {
+			return nil, errors.Join(err, dlqErr)
+		}
+	}
+}
+
+// Commit implements broker.Subscriber. Kafka offsets are cumulative, so the
+// partition is only committed up to the highest offset below which every
+// fetched record has been committed; messages committed out of order wait
+// for the earlier ones. Committing an untracked message is a no-op.
+func (s *Subscriber) Commit(ctx context.Context, message broker.Message) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	record, err := mapMessageToCommit(message)
+	if err != nil {
+		return err
+	}
+
+	return s.commit(ctx, record)
+}
+
+// deadLetter writes the undecodable record to the dead-letter topic and
+// commits it.
+func (s *Subscriber) deadLetter(ctx context.Context, record kafka.Message, cause error) error {
+	logger.Of(ctx).WarnS("Kafka::Subscriber::DeadLetter",
+		logger.WithValue("topic", record.Topic),
+		logger.WithValue("partition", record.Partition),
+		logger.WithValue("offset", record.Offset),
+		logger.WithValue("error", cause))
+
+	headers := append(slices.Clip(record.Headers), kafka.Header{Key: DeadLetterReason, Value: []byte(cause.Error())})
+	dead := kafka.Message{
+		Topic:   s.options.deadLetterTopic,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	}
+	if err := s.options.deadLetter.WriteMessages(ctx, dead); err != nil {
+		return err
+	}
+
+	return s.commit(ctx, kafka.Message{Topic: record.Topic, Partition: record.Partition, Offset: record.Offset})
+}
+
+// fetch returns the next record once it is tracked. Fetching and tracking
+// are serialized so each partition is tracked in fetch order.
+func (s *Subscriber) fetch(ctx context.Context) (kafka.Message, error) {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	record, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	s.track(ctx, record)
+
+	return record, nil
+}
+
+// track adds record to the uncommitted offsets of its partition. The
+// partition's state is reset when the record is not past the last fetched
+// offset, as when the partition was reassigned and is read again from its
+// committed offset, or when it lies WithMaxUncommitted offsets or more past
+// the oldest uncommitted one, as when another consumer committed past it or a
+// record is never committed.
+func (s *Subscriber) track(ctx context.Context, record kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := partition{topic: record.Topic, id: record.Partition}
+	p, ok := s.pending[key]
+	if ok && (record.Offset <= p.last ||
+		len(p.fetched) > 0 && record.Offset-p.fetched[0] >= int64(s.options.maxUncommitted)) {
+		if len(p.fetched) > 0 {
+			logger.Of(ctx).WarnS("Kafka::Subscriber::Track",
+				logger.WithValue("topic", record.Topic),
+				logger.WithValue("partition", record.Partition),
+				logger.WithValue("offset", record.Offset),
+				logger.WithValue("abandoned_from", p.fetched[0]),
+				logger.WithValue("abandoned", len(p.fetched)))
+		}
+		ok = false
+	}
+	if !ok {
+		p = &offsets{done: make(map[int64]struct{})}
+		s.pending[key] = p
+	}
+
+	p.fetched = append(p.fetched, record.Offset)
+	p.last = record.Offset
+}
+
+// commit marks record as processed and commits its partition up to the
+// highest contiguous processed offset. The lock is held while committing so
+// offsets are never sent out of order.
+func (s *Subscriber) commit(ctx context.Context, record kafka.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[partition{topic: record.Topic, id: record.Partition}]
+	if !ok {
+		return nil
+	}
+	if _, found := slices.BinarySearch(p.fetched, record.Offset); !found {
+		return nil
+	}
+	p.done[record.Offset] = struct{}{}
+
+	n := 0
+	for n < len(p.fetched) {
+		if _, ok := p.done[p.fetched[n]]; !ok {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	record.Offset = p.fetched[n-1]
+	if err := s.reader.CommitMessages(ctx, record); err != nil {
+		return err
+	}
+
+	for _, offset := range p.fetched[:n] {
+		delete(p.done, offset)
+	}
+	p.fetched = slices.Delete(p.fetched, 0, n)
+
+	return nil
+}
+
+func (s *Subscriber) Close(ctx context.Context) {
+	if err := s.reader.Close(); err != nil {
+		logger.Of(ctx).ErrorS("Kafka::Subscriber::Close", logger.WithValue("error", err))
+	}
+}
+
+// NewSubscriber joins groupID and consumes topic from brokers. Partitions
+// are balanced across every subscriber sharing the group.
+func NewSubscriber(brokers []string, groupID, topic string, opts ...SubscriberOption) *Subscriber {
+	options := newSubscriberOption(opts...)
+
+	if options.reader == nil {
+		options.reader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			GroupID: groupID,
+			Topic:   topic,
+		})
+	}
+
+	return &Subscriber{
+		options: options,
+		reader:  options.reader,
+		pending: make(map[partition]*offsets),
+	}
+}
+
+func mapRecordToMessage(options *subscriberOptions, record kafka.Message) (broker.Message, error) {
+	attr := newAttributes()
+	for _, h := range record.Headers {
+		if isReserved(h.Key) {
+			continue
+		}
+		attr.Add(h.Key, string(h.Value))
+	}
+
+	attr.Add(MessageTopic, record.Topic)
+	attr.Add(MessagePartition, strconv.Itoa(record.Partition))
+	attr.Add(MessageOffset, strconv.FormatInt(record.Offset, 10))
+	if record.Key != nil {
+		attr.Add(MessageKey, string(record.Key))
+	}
+
+	var (
+		payload any
+		err     error
+	)
+	if options.contentDecoder != nil {
+		payload, err = options.contentDecoder(attr, record.Value)
+	} else {
+		payload, err = options.decoder(record.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &message{
+		data:      payload,
+		attr:      attr,
+		createdAt: record.Time,
+	}, nil
+}
+
+func mapMessageToCommit(message broker.Message) (kafka.Message, error) {
+	attr := message.Attributes()
+
+	partition, err := strconv.Atoi(attr.Get(MessagePartition))
+	if err != nil {
+		return kafka.Message{}, ErrNoOffset
+	}
+
+	offset, err := strconv.ParseInt(attr.Get(MessageOffset), 10, 64)
+	if err != nil {
+		return kafka.Message{}, ErrNoOffset
+	}
+
+	return kafka.Message{
+		Topic:     attr.Get(MessageTopic),
+		Partition: partition,
+		Offset:    offset,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+type subscriberOptions struct {
+	decoder         broker.Decoder
+	contentDecoder  broker.ContentDecoder
+	reader          Reader
+	deadLetter      Writer
+	deadLetterTopic string
+	maxUncommitted  int
+}
+
+const defaultMaxUncommitted = 10_000
+
+type SubscriberOption func(*subscriberOptions)
+
+func WithDecoder(decoder broker.Decoder) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.decoder = decoder
+	}
+}
+
+// WithContentDecoder decodes each payload according to its
+// broker.ContentTypeAttribute header. It takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.contentDecoder = decoder
+	}
+}
+
+func WithDecoderTarget(typeof any) SubscriberOption {
+	return func(s *subscriberOptions) {
+		v := reflect.TypeOf(typeof)
+
+		s.decoder = func(b []byte) (any, error) {
+			target := reflect.New(v).Interface()
+			if err := json.Unmarshal(b, target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		}
+	}
+}
+
+// WithReader replaces the kafka.Reader built by NewSubscriber, e.g. to tune
+// fetch sizes or use a fake in tests.
+func WithReader(reader Reader) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.reader = reader
+	}
+}
+
+// WithDeadLetter writes records that fail to decode, unchanged and with a
+// DeadLetterReason header, to topic through writer and then commits them.
+func WithDeadLetter(writer Writer, topic string) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.deadLetter = writer
+		so.deadLetterTopic = topic
+	}
+}
+
+// WithMaxUncommitted bounds how far past its oldest uncommitted offset a
+// partition is read before that offset and the ones tracked after it are
+// abandoned, so a record that is never committed no longer holds back every
+// later commit. Values below 1 are ignored.
+func WithMaxUncommitted(n int) SubscriberOption {
+	return func(so *subscriberOptions) {
+		if n > 0 {
+			so.maxUncommitted = n
+		}
+	}
+}
+
+func newSubscriberOption(opts ...SubscriberOption) *subscriberOptions {
+	options := &subscriberOptions{
+		maxUncommitted: defaultMaxUncommitted,
+		decoder: func(b []byte) (any, error) {
+			var target any
+
+			if err := json.Unmarshal(b, &target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// LogOption configures LogPublish and LogHandle.
+type LogOption func(*logOptions)
+
+type logOptions struct {
+	attributes []string
+}
+
+// WithLogAttributes adds the given message attributes, such as a message ID,
+// to every log entry.
+func WithLogAttributes(keys ...string) LogOption {
+	return func(o *logOptions) {
+		o.attributes = append(o.attributes, keys...)
+	}
+}
+
+// LogPublish logs every Publish call through core/logger: failures at error
+// level and successes at debug level, with the message count and latency.
+func LogPublish(opts ...LogOption) broker.PublisherMiddleware {
+	o := &logOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next broker.Publisher) broker.Publisher {
+		return broker.PublisherFunc(func(ctx context.Context, messages ...broker.Message) error {
+			start := time.Now()
+			err := next.Publish(ctx, messages...)
+
+			fields := []logger.Option{
+				logger.WithValue("messages", len(messages)),
+				logger.WithValue("latency_ms", time.Since(start).Milliseconds()),
+			}
+			if len(messages) == 1 {
+				fields = append(fields, o.fields(messages[0])...)
+			}
+
+			if err != nil {
+				fields = append(fields, logger.WithValue("error", err))
+				logger.Of(ctx).ErrorS("Broker::Publish", fields...)
+				return err
+			}
+
+			logger.Of(ctx).DebugS("Broker::Publish", fields...)
+			return nil
+		})
+	}
+}
+
+// LogHandle logs the handling of every received message: handler errors at
+// error level and successes at debug level, with the latency.
+func LogHandle(opts ...LogOption) broker.SubscriberMiddleware {
+	o := &logOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next broker.MessageHandler) broker.MessageHandler {
+		return func(ctx context.Context, m broker.Message) error {
+			start := time.Now()
+			err := next(ctx, m)
+
+			fields := append(o.fields(m),
+				logger.WithValue("latency_ms", time.Since(start).Milliseconds()))
+
+			if err != nil {
+				fields = append(fields, logger.WithValue("error", err))
+				logger.Of(ctx).ErrorS("Broker::Handle", fields...)
+				return err
+			}
+
+			logger.Of(ctx).DebugS("Broker::Handle", fields...)
+			return nil
+		}
+	}
+}
+
+func (o *logOptions) fields(m broker.Message) []logger.Option {
+	fields := make([]logger.Option, 0, len(o.attributes))
+	for _, key := range o.attributes {
+		if value, ok := m.Attributes().Lookup(key); ok {
+			fields = append(fields, logger.WithValue(key, value))
+		}
+	}
+	return fields
+}
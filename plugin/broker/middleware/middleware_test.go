@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+	"github.com/aawadallak/go-core-kit/plugin/broker/subscriber"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	b := inmem.New()
+	propagator := WithPropagator(propagation.TraceContext{})
+	pub := broker.ChainPublisher(b, InjectTraceContext(propagator), LogPublish())
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	if err := pub.Publish(ctx, inmem.NewMessage(order{ID: "o-1"}, "orders")); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	received := make(chan trace.SpanContext, 1)
+	sub := b.NewSubscriber("orders", inmem.WithDecoderTarget(order{}))
+	handler := subscriber.NewHandler(sub, func(ctx context.Context, _ order) error {
+		received <- trace.SpanContextFromContext(ctx)
+		return nil
+	}, subscriber.WithMiddleware(ExtractTraceContext(propagator), LogHandle()))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = handler.Start(runCtx, 1)
+	defer func() {
+		cancel()
+		sub.Close(context.Background())
+		handler.Stop()
+	}()
+
+	select {
+	case got := <-received:
+		if got.TraceID() != parent.TraceID() || !got.IsRemote() {
+			t.Fatalf("expected remote span context with trace %s, got %+v", parent.TraceID(), got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for handler")
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) broker.PublisherMiddleware {
+		return func(next broker.Publisher) broker.Publisher {
+			return broker.PublisherFunc(func(ctx context.Context, messages ...broker.Message) error {
+				calls = append(calls, name)
+				return next.Publish(ctx, messages...)
+			})
+		}
+	}
+	final := broker.PublisherFunc(func(context.Context, ...broker.Message) error {
+		calls = append(calls, "publisher")
+		return nil
+	})
+
+	_ = broker.ChainPublisher(final, record("outer"), record("inner")).Publish(context.Background())
+
+	if got := strings.Join(calls, ","); got != "outer,inner,publisher" {
+		t.Fatalf("unexpected call order %q", got)
+	}
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	b := inmem.New()
+	pub := broker.ChainPublisher(b, MaxPayloadSize(16, nil))
+
+	err := pub.Publish(context.Background(),
+		inmem.NewMessage(order{ID: "1"}, "orders"),
+		inmem.NewMessage(order{ID: "a-much-longer-identifier"}, "orders"),
+	)
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+	if b.Len("orders") != 0 {
+		t.Fatalf("expected nothing to be published, got %d", b.Len("orders"))
+	}
+
+	if err := pub.Publish(context.Background(), inmem.NewMessage(order{ID: "1"}, "orders")); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package middleware provides broker.PublisherMiddleware and
+// broker.SubscriberMiddleware implementations for tracing, logging and
+// payload validation.
+package middleware
+
+import (
+	"context"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TraceOption configures InjectTraceContext and ExtractTraceContext.
+type TraceOption func(*traceOptions)
+
+type traceOptions struct {
+	propagator propagation.TextMapPropagator
+}
+
+// WithPropagator overrides the propagator, which defaults to the global one
+// registered with otel.SetTextMapPropagator.
+func WithPropagator(p propagation.TextMapPropagator) TraceOption {
+	return func(o *traceOptions) {
+		o.propagator = p
+	}
+}
+
+func newTraceOptions(opts ...TraceOption) *traceOptions {
+	o := &traceOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *traceOptions) textMapPropagator() propagation.TextMapPropagator {
+	if o.propagator != nil {
+		return o.propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// InjectTraceContext writes the trace context of ctx into the attributes of
+// every published message, e.g. as a "traceparent" attribute.
+func InjectTraceContext(opts ...TraceOption) broker.PublisherMiddleware {
+	o := newTraceOptions(opts...)
+
+	return func(next broker.Publisher) broker.Publisher {
+		return broker.PublisherFunc(func(ctx context.Context, messages ...broker.Message) error {
+			propagator := o.textMapPropagator()
+			for _, m := range messages {
+				propagator.Inject(ctx, attributesCarrier{m.Attributes()})
+			}
+			return next.Publish(ctx, messages...)
+		})
+	}
+}
+
+// ExtractTraceContext continues the trace carried in the message attributes,
+// so spans started by the handler are children of the publisher's span.
+func ExtractTraceContext(opts ...TraceOption) broker.SubscriberMiddleware {
+	o := newTraceOptions(opts...)
+
+	return func(next broker.MessageHandler) broker.MessageHandler {
+		return func(ctx context.Context, m broker.Message) error {
+			ctx = o.textMapPropagator().Extract(ctx, attributesCarrier{m.Attributes()})
+			return next(ctx, m)
+		}
+	}
+}
+
+// attributesCarrier adapts broker.Attributes to propagation.TextMapCarrier.
+type attributesCarrier struct {
+	attrs broker.Attributes
+}
+
+var _ propagation.TextMapCarrier = attributesCarrier{}
+
+func (c attributesCarrier) Get(key string) string {
+	return c.attrs.Get(key)
+}
+
+func (c attributesCarrier) Set(key, value string) {
+	c.attrs.Delete(key)
+	c.attrs.Add(key, value)
+}
+
+func (c attributesCarrier) Keys() []string {
+	values := c.attrs.Values()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return keys
+}
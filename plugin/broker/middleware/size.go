@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// ErrPayloadTooLarge is returned by MaxPayloadSize when a message exceeds the limit.
+var ErrPayloadTooLarge = errors.New("message payload too large")
+
+// MaxPayloadSize rejects the whole Publish call when any message encodes to
+// more than limit bytes, before anything is sent. encoder should match the
+// publisher's; nil means JSON.
+func MaxPayloadSize(limit int, encoder broker.Encoder) broker.PublisherMiddleware {
+	if encoder == nil {
+		encoder = func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		}
+	}
+
+	return func(next broker.Publisher) broker.Publisher {
+		return broker.PublisherFunc(func(ctx context.Context, messages ...broker.Message) error {
+			for i, m := range messages {
+				body, err := encoder(m)
+				if err != nil {
+					return err
+				}
+				if len(body) > limit {
+					return fmt.Errorf("%w: message %d is %d bytes, limit is %d",
+						ErrPayloadTooLarge, i, len(body), limit)
+				}
+			}
+			return next.Publish(ctx, messages...)
+		})
+	}
+}
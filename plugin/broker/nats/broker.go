@@ -0,0 +1,238 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrNoSubject is returned by BrokerPublisher for messages without a subject.
+var ErrNoSubject = errors.New("message has no subject attribute")
+
+// msgPublisher is the subset of *nats.Conn used by BrokerPublisher.
+type msgPublisher interface {
+	PublishMsg(msg *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+}
+
+// msgSubscription is the subset of *nats.Subscription used by BrokerSubscriber.
+type msgSubscription interface {
+	NextMsgWithContext(ctx context.Context) (*nats.Msg, error)
+	Unsubscribe() error
+}
+
+type brokerOptions struct {
+	encoder        broker.Encoder
+	decoder        broker.Decoder
+	contentDecoder broker.ContentDecoder
+}
+
+type BrokerOption func(*brokerOptions)
+
+func WithEncoder(encoder broker.Encoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.encoder = encoder
+	}
+}
+
+func WithDecoder(decoder broker.Decoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.decoder = decoder
+	}
+}
+
+// WithContentDecoder decodes each payload according to its
+// broker.ContentTypeAttribute header. It takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.contentDecoder = decoder
+	}
+}
+
+// WithDecoderTarget decodes JSON payloads into a new *T, the shape expected
+// by subscriber.Handler[T].
+func WithDecoderTarget(typeof any) BrokerOption {
+	return func(o *brokerOptions) {
+		v := reflect.TypeOf(typeof)
+
+		o.decoder = func(b []byte) (any, error) {
+			target := reflect.New(v).Interface()
+			if err := json.Unmarshal(b, target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		}
+	}
+}
+
+func newBrokerOptions(opts ...BrokerOption) *brokerOptions {
+	options := &brokerOptions{
+		encoder: func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		},
+		decoder: func(b []byte) (any, error) {
+			var target any
+
+			if err := json.Unmarshal(b, &target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// BrokerPublisher implements broker.Publisher on a NATS connection.
+type BrokerPublisher struct {
+	options *brokerOptions
+	conn    msgPublisher
+}
+
+var _ broker.Publisher = (*BrokerPublisher)(nil)
+
+func NewBrokerPublisher(conn *nats.Conn, opts ...BrokerOption) *BrokerPublisher {
+	return &BrokerPublisher{
+		options: newBrokerOptions(opts...),
+		conn:    conn,
+	}
+}
+
+// Publish implements broker.Publisher. Messages are routed by their subject
+// attribute and the connection is flushed before returning.
+func (p *BrokerPublisher) Publish(ctx context.Context, messages ...broker.Message) error {
+	msgs := make([]*nats.Msg, 0, len(messages))
+	for _, m := range messages {
+		msg, err := mapToMsg(p.options.encoder, m)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	for _, msg := range msgs {
+		if err := p.conn.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+
+	// nats.FlushWithContext requires a context with deadline.
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		return p.conn.FlushWithContext(timeoutCtx)
+	}
+
+	return p.conn.FlushWithContext(ctx)
+}
+
+// BrokerSubscriber implements broker.Subscriber on a core NATS subscription.
+// Core NATS has no acknowledgements, so delivery is at most once and Commit
+// is a no-op; use natsjetstream when messages must survive a failed handler.
+type BrokerSubscriber struct {
+	options *brokerOptions
+	sub     msgSubscription
+}
+
+var _ broker.Subscriber = (*BrokerSubscriber)(nil)
+
+// NewBrokerSubscriber subscribes to subject. With a non-empty queueGroup
+// messages are load-balanced across every subscriber of the group.
+func NewBrokerSubscriber(conn *nats.Conn, subject, queueGroup string, opts ...BrokerOption) (*BrokerSubscriber, error) {
+	var (
+		sub *nats.Subscription
+		err error
+	)
+
+	if queueGroup != "" {
+		sub, err = conn.QueueSubscribeSync(subject, queueGroup)
+	} else {
+		sub, err = conn.SubscribeSync(subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &BrokerSubscriber{
+		options: newBrokerOptions(opts...),
+		sub:     sub,
+	}, nil
+}
+
+func (s *BrokerSubscriber) Subscribe(ctx context.Context) (broker.Message, error) {
+	msg, err := s.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapMsgToMessage(s.options, msg)
+}
+
+func (s *BrokerSubscriber) Commit(ctx context.Context, _ broker.Message) error {
+	return ctx.Err()
+}
+
+func (s *BrokerSubscriber) Close(_ context.Context) {
+	_ = s.sub.Unsubscribe()
+}
+
+func mapToMsg(encoder broker.Encoder, m broker.Message) (*nats.Msg, error) {
+	subject, ok := m.Attributes().Lookup(MessageSubject)
+	if !ok || subject == "" {
+		return nil, ErrNoSubject
+	}
+
+	data, err := encoder(m)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for key, values := range m.Attributes().Values() {
+		if key == MessageSubject {
+			continue
+		}
+		for _, v := range values {
+			msg.Header.Add(key, v)
+		}
+	}
+
+	return msg, nil
+}
+
+func mapMsgToMessage(options *brokerOptions, msg *nats.Msg) (broker.Message, error) {
+	attr := newAttributes()
+	for key, values := range msg.Header {
+		for _, v := range values {
+			attr.Add(key, v)
+		}
+	}
+	attr.Delete(MessageSubject)
+	attr.Add(MessageSubject, msg.Subject)
+
+	payload, err := options.decode(attr, msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message{data: payload, attr: attr}, nil
+}
+
+func (o *brokerOptions) decode(attr broker.Attributes, data []byte) (any, error) {
+	if o.contentDecoder != nil {
+		return o.contentDecoder(attr, data)
+	}
+	return o.decoder(data)
+}
@@ -0,0 +1,75 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+// loopback delivers every published message to its own subscription.
+type loopback struct {
+	msgs chan *nats.Msg
+}
+
+func (l *loopback) PublishMsg(msg *nats.Msg) error {
+	l.msgs <- msg
+	return nil
+}
+
+func (l *loopback) FlushWithContext(ctx context.Context) error { return ctx.Err() }
+
+func (l *loopback) NextMsgWithContext(ctx context.Context) (*nats.Msg, error) {
+	select {
+	case msg := <-l.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *loopback) Unsubscribe() error { return nil }
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestBrokerPublishSubscribe(t *testing.T) {
+	ctx := context.Background()
+	conn := &loopback{msgs: make(chan *nats.Msg, 1)}
+	pub := &BrokerPublisher{options: newBrokerOptions(), conn: conn}
+	sub := &BrokerSubscriber{options: newBrokerOptions(WithDecoderTarget(order{})), sub: conn}
+
+	err := pub.Publish(ctx, NewMessage(order{ID: "o-1"}, "orders.created", WithHeader("X-Trace-ID", "trace-1")))
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if got := msg.Payload().(*order); got.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	if got := msg.Attributes().Get("X-Trace-ID"); got != "trace-1" {
+		t.Fatalf("expected header to round-trip, got %q", got)
+	}
+	if got := msg.Attributes().Get(MessageSubject); got != "orders.created" {
+		t.Fatalf("expected subject attribute, got %q", got)
+	}
+	if err := sub.Commit(ctx, msg); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+}
+
+func TestBrokerPublishRequiresSubject(t *testing.T) {
+	conn := &loopback{msgs: make(chan *nats.Msg, 1)}
+	pub := &BrokerPublisher{options: newBrokerOptions(), conn: conn}
+
+	err := pub.Publish(context.Background(), NewMessage(order{ID: "o-1"}, ""))
+	if !errors.Is(err, ErrNoSubject) {
+		t.Fatalf("expected ErrNoSubject, got %v", err)
+	}
+}
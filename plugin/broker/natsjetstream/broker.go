@@ -0,0 +1,423 @@
+package natsjetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// defaultAckWait is the JetStream default for a consumer's AckWait.
+const defaultAckWait = 30 * time.Second
+
+var (
+	// ErrNoSubject is returned by BrokerPublisher for messages without a subject.
+	ErrNoSubject = errors.New("message has no subject attribute")
+	// ErrInvalidReceipt is returned by Commit for messages that are not in
+	// flight on this subscriber, e.g. already committed.
+	ErrInvalidReceipt = errors.New("receipt handle is invalid or already committed")
+)
+
+// msgPublisher is the subset of jetstream.JetStream used by BrokerPublisher.
+type msgPublisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// fetcher is the subset of jetstream.Consumer used by BrokerSubscriber.
+type fetcher interface {
+	Fetch(batch int, opts ...jetstream.FetchOpt) (jetstream.MessageBatch, error)
+}
+
+type brokerOptions struct {
+	encoder        broker.Encoder
+	decoder        broker.Decoder
+	contentDecoder broker.ContentDecoder
+	fetchMaxWait   time.Duration
+	ackWait        time.Duration
+}
+
+type BrokerOption func(*brokerOptions)
+
+func WithEncoder(encoder broker.Encoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.encoder = encoder
+	}
+}
+
+func WithDecoder(decoder broker.Decoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.decoder = decoder
+	}
+}
+
+// WithContentDecoder decodes each payload according to its
+// broker.ContentTypeAttribute header. It takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.contentDecoder = decoder
+	}
+}
+
+// WithDecoderTarget decodes JSON payloads into a new *T, the shape expected
+// by subscriber.Handler[T].
+func WithDecoderTarget(typeof any) BrokerOption {
+	return func(o *brokerOptions) {
+		v := reflect.TypeOf(typeof)
+
+		o.decoder = func(b []byte) (any, error) {
+			target := reflect.New(v).Interface()
+			if err := json.Unmarshal(b, target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		}
+	}
+}
+
+// WithFetchMaxWait bounds each pull request issued by Subscribe. Defaults to 1 second.
+func WithFetchMaxWait(wait time.Duration) BrokerOption {
+	return func(o *brokerOptions) {
+		o.fetchMaxWait = wait
+	}
+}
+
+// WithAckWait sets how long an uncommitted message stays tracked before
+// the subscriber forgets it, since the server redelivers it after the
+// consumer's AckWait under a new receipt. Defaults to the consumer's AckWait,
+// or 30 seconds.
+func WithAckWait(wait time.Duration) BrokerOption {
+	return func(o *brokerOptions) {
+		o.ackWait = wait
+	}
+}
+
+func newBrokerOptions(opts ...BrokerOption) *brokerOptions {
+	options := &brokerOptions{
+		encoder: func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		},
+		decoder: func(b []byte) (any, error) {
+			var target any
+
+			if err := json.Unmarshal(b, &target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		},
+		fetchMaxWait: time.Second,
+		ackWait:      defaultAckWait,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// BrokerPublisher implements broker.Publisher on JetStream. Each message is
+// published to its subject attribute and waits for the stream's ack.
+type BrokerPublisher struct {
+	options *brokerOptions
+	js      msgPublisher
+}
+
+var _ broker.Publisher = (*BrokerPublisher)(nil)
+
+func NewBrokerPublisher(js jetstream.JetStream, opts ...BrokerOption) *BrokerPublisher {
+	return &BrokerPublisher{
+		options: newBrokerOptions(opts...),
+		js:      js,
+	}
+}
+
+func (p *BrokerPublisher) Publish(ctx context.Context, messages ...broker.Message) error {
+	msgs := make([]*nats.Msg, 0, len(messages))
+	for _, m := range messages {
+		msg, err := mapToMsg(p.options.encoder, m)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	for _, msg := range msgs {
+		if _, err := p.js.PublishMsg(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BrokerSubscriber implements broker.Subscriber on a JetStream pull consumer
+// with explicit acks. Commit acks the message; uncommitted messages are
+// redelivered after the consumer's AckWait, up to MaxDeliver times.
+type BrokerSubscriber struct {
+	options  *brokerOptions
+	consumer fetcher
+
+	mu        sync.Mutex
+	inflight  map[string]inflightMsg
+	nextSweep time.Time
+}
+
+// inflightMsg is a received message and the time after which the server
+// considers it unacked and redelivers it.
+type inflightMsg struct {
+	msg      jetstream.Msg
+	deadline time.Time
+}
+
+var (
+	_ broker.BatchSubscriber = (*BrokerSubscriber)(nil)
+	_ broker.Extender        = (*BrokerSubscriber)(nil)
+)
+
+func NewBrokerSubscriber(consumer jetstream.Consumer, opts ...BrokerOption) *BrokerSubscriber {
+	if info := consumer.CachedInfo(); info != nil && info.Config.AckWait > 0 {
+		opts = append([]BrokerOption{WithAckWait(info.Config.AckWait)}, opts...)
+	}
+
+	return newBrokerSubscriber(consumer, opts...)
+}
+
+func newBrokerSubscriber(consumer fetcher, opts ...BrokerOption) *BrokerSubscriber {
+	return &BrokerSubscriber{
+		options:  newBrokerOptions(opts...),
+		consumer: consumer,
+		inflight: make(map[string]inflightMsg),
+	}
+}
+
+// Subscribe pulls one message, blocking until one is available or ctx is
+// done. Payloads that can never be decoded are terminated; other decode
+// errors, such as an unknown content type or schema version, leave the
+// message unacked so it is redelivered up to the consumer's MaxDeliver.
+func (s *BrokerSubscriber) Subscribe(ctx context.Context) (broker.Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		batch, err := s.consumer.Fetch(1, jetstream.FetchMaxWait(s.options.fetchMaxWait))
+		if err != nil {
+			return nil, err
+		}
+
+		if msg, ok := <-batch.Messages(); ok {
+			return s.receive(msg)
+		}
+
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return nil, err
+		}
+	}
+}
+
+// SubscribeBatch pulls up to limit messages in one Fetch, blocking until at
+// least one is available or ctx is done. Messages that fail to decode are
+// settled as in Subscribe and reported in the returned error alongside the
+// decoded ones.
+func (s *BrokerSubscriber) SubscribeBatch(ctx context.Context, limit int) ([]broker.Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		batch, err := s.consumer.Fetch(max(limit, 1), jetstream.FetchMaxWait(s.options.fetchMaxWait))
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			messages []broker.Message
+			errs     []error
+		)
+		for msg := range batch.Messages() {
+			message, err := s.receive(msg)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			messages = append(messages, message)
+		}
+
+		if len(messages) > 0 || len(errs) > 0 {
+			return messages, errors.Join(errs...)
+		}
+
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return nil, err
+		}
+	}
+}
+
+func (s *BrokerSubscriber) Commit(ctx context.Context, message broker.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	receipt := message.Attributes().Get(MessageReceiptHandle)
+
+	s.mu.Lock()
+	tracked, ok := s.inflight[receipt]
+	delete(s.inflight, receipt)
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrInvalidReceipt
+	}
+
+	return tracked.msg.Ack()
+}
+
+// Extend sends an in-progress ack, which resets the consumer's AckWait; d is
+// not used.
+func (s *BrokerSubscriber) Extend(ctx context.Context, message broker.Message, _ time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	receipt := message.Attributes().Get(MessageReceiptHandle)
+
+	s.mu.Lock()
+	tracked, ok := s.inflight[receipt]
+	if ok {
+		tracked.deadline = time.Now().Add(s.options.ackWait)
+		s.inflight[receipt] = tracked
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrInvalidReceipt
+	}
+
+	return tracked.msg.InProgress()
+}
+
+// CommitBatch acks every message, returning the joined errors of those that failed.
+func (s *BrokerSubscriber) CommitBatch(ctx context.Context, messages []broker.Message) error {
+	var errs []error
+	for _, message := range messages {
+		if err := s.Commit(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close naks every uncommitted message so it is redelivered without waiting
+// for AckWait.
+func (s *BrokerSubscriber) Close(_ context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for receipt, tracked := range s.inflight {
+		_ = tracked.msg.Nak()
+		delete(s.inflight, receipt)
+	}
+}
+
+// receive decodes msg and tracks it until it is committed or its AckWait
+// passes.
+func (s *BrokerSubscriber) receive(msg jetstream.Msg) (broker.Message, error) {
+	message, err := mapMsgToMessage(s.options, msg)
+	if err != nil {
+		if broker.IsPoison(err) {
+			_ = msg.TermWithReason(err.Error())
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	s.inflight[msg.Reply()] = inflightMsg{msg: msg, deadline: now.Add(s.options.ackWait)}
+
+	return message, nil
+}
+
+// sweep forgets messages past their AckWait: the server has redelivered them
+// under a new receipt, so they can no longer be committed through the old
+// one. It runs at most once per AckWait. s.mu must be held.
+func (s *BrokerSubscriber) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(s.options.ackWait)
+
+	for receipt, tracked := range s.inflight {
+		if now.After(tracked.deadline) {
+			delete(s.inflight, receipt)
+		}
+	}
+}
+
+func mapToMsg(encoder broker.Encoder, m broker.Message) (*nats.Msg, error) {
+	subject, ok := m.Attributes().Lookup(MessageSubject)
+	if !ok || subject == "" {
+		return nil, ErrNoSubject
+	}
+
+	data, err := encoder(m)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for key, values := range m.Attributes().Values() {
+		switch key {
+		case MessageSubject, MessageReceiptHandle, MessageReceiveCount:
+			continue
+		}
+		for _, v := range values {
+			msg.Header.Add(key, v)
+		}
+	}
+
+	return msg, nil
+}
+
+func mapMsgToMessage(options *brokerOptions, msg jetstream.Msg) (broker.Message, error) {
+	attr := newAttributes()
+	for key, values := range msg.Headers() {
+		for _, v := range values {
+			attr.Add(key, v)
+		}
+	}
+	attr.Delete(MessageSubject)
+	attr.Add(MessageSubject, msg.Subject())
+	attr.Add(MessageReceiptHandle, msg.Reply())
+	if meta, err := msg.Metadata(); err == nil {
+		attr.Add(MessageReceiveCount, strconv.FormatUint(meta.NumDelivered, 10))
+	}
+
+	payload, err := options.decode(attr, msg.Data())
+	if err != nil {
+		return nil, err
+	}
+
+	return &message{data: payload, attr: attr}, nil
+}
+
+func (o *brokerOptions) decode(attr broker.Attributes, data []byte) (any, error) {
+	if o.contentDecoder != nil {
+		return o.contentDecoder(attr, data)
+	}
+	return o.decoder(data)
+}
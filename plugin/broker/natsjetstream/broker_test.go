@@ -0,0 +1,260 @@
+package natsjetstream
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type fakeMsg struct {
+	jetstream.Msg
+	msg       *nats.Msg
+	delivered uint64
+	acked     bool
+	naked     bool
+	termed    bool
+	progress  int
+}
+
+func (m *fakeMsg) Data() []byte         { return m.msg.Data }
+func (m *fakeMsg) Headers() nats.Header { return m.msg.Header }
+func (m *fakeMsg) Subject() string      { return m.msg.Subject }
+func (m *fakeMsg) Reply() string        { return m.msg.Reply }
+func (m *fakeMsg) Ack() error           { m.acked = true; return nil }
+func (m *fakeMsg) Nak() error           { m.naked = true; return nil }
+func (m *fakeMsg) InProgress() error    { m.progress++; return nil }
+
+func (m *fakeMsg) TermWithReason(string) error { m.termed = true; return nil }
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+
+type fakeBatch struct {
+	msgs chan jetstream.Msg
+}
+
+func (b *fakeBatch) Messages() <-chan jetstream.Msg { return b.msgs }
+func (b *fakeBatch) Error() error                   { return nil }
+
+// fakeStream stores published messages and serves them to Fetch in order.
+type fakeStream struct {
+	mu      sync.Mutex
+	pending []*fakeMsg
+}
+
+func (f *fakeStream) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	msg.Reply = "$JS.ACK." + strconv.Itoa(len(f.pending))
+	f.pending = append(f.pending, &fakeMsg{msg: msg, delivered: 1})
+	return &jetstream.PubAck{}, nil
+}
+
+func (f *fakeStream) Fetch(n int, _ ...jetstream.FetchOpt) (jetstream.MessageBatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	batch := &fakeBatch{msgs: make(chan jetstream.Msg, n)}
+	for ; n > 0 && len(f.pending) > 0; n-- {
+		batch.msgs <- f.pending[0]
+		f.pending = f.pending[1:]
+	}
+	close(batch.msgs)
+	return batch, nil
+}
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestBrokerPublishSubscribeCommit(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{}
+	pub := &BrokerPublisher{options: newBrokerOptions(), js: stream}
+	sub := newBrokerSubscriber(stream, WithDecoderTarget(order{}))
+
+	err := pub.Publish(ctx,
+		NewMessage(order{ID: "o-1"}, "orders.created", WithHeader("X-Trace-ID", "trace-1")),
+		NewMessage(order{ID: "o-2"}, "orders.created"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	first, second := stream.pending[0], stream.pending[1]
+
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if got := msg.Payload().(*order); got.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	attr := msg.Attributes()
+	if attr.Get("X-Trace-ID") != "trace-1" || attr.Get(MessageSubject) != "orders.created" {
+		t.Fatalf("headers not mapped to attributes: %v", attr.Values())
+	}
+	if attr.Get(MessageReceiveCount) != "1" {
+		t.Fatalf("expected receive count 1, got %q", attr.Get(MessageReceiveCount))
+	}
+
+	if err := sub.Commit(ctx, msg); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if !first.acked {
+		t.Fatal("expected commit to ack the message")
+	}
+	if err := sub.Commit(ctx, msg); !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("expected ErrInvalidReceipt on second commit, got %v", err)
+	}
+
+	if _, err := sub.Subscribe(ctx); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	sub.Close(ctx)
+	if !second.naked || second.acked {
+		t.Fatal("expected close to nak the uncommitted message")
+	}
+}
+
+func TestBrokerSubscribeHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sub := newBrokerSubscriber(&fakeStream{})
+	if _, err := sub.Subscribe(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBrokerSubscribeBatch(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{}
+	pub := &BrokerPublisher{options: newBrokerOptions(), js: stream}
+	sub := newBrokerSubscriber(stream, WithDecoderTarget(order{}))
+
+	for _, id := range []string{"o-1", "o-2", "o-3"} {
+		if err := pub.Publish(ctx, NewMessage(order{ID: id}, "orders.created")); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+	published := append([]*fakeMsg(nil), stream.pending...)
+
+	msgs, err := sub.SubscribeBatch(ctx, 2)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Payload().(*order).ID != "o-1" || msgs[1].Payload().(*order).ID != "o-2" {
+		t.Fatalf("unexpected batch %v", msgs)
+	}
+
+	if err := sub.CommitBatch(ctx, msgs); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if !published[0].acked || !published[1].acked || published[2].acked {
+		t.Fatal("expected only the fetched messages to be acked")
+	}
+
+	msgs, err = sub.SubscribeBatch(ctx, 10)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected the remaining message, got %v, %v", msgs, err)
+	}
+}
+
+func TestBrokerExtendSendsInProgress(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{}
+	pub := &BrokerPublisher{options: newBrokerOptions(), js: stream}
+	sub := newBrokerSubscriber(stream, WithDecoderTarget(order{}))
+
+	if err := pub.Publish(ctx, NewMessage(order{ID: "o-1"}, "orders.created")); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	fetched := stream.pending[0]
+
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if err := sub.Extend(ctx, msg, time.Minute); err != nil {
+		t.Fatalf("unexpected extend error: %v", err)
+	}
+	if fetched.progress != 1 {
+		t.Fatalf("expected one in-progress ack, got %d", fetched.progress)
+	}
+
+	if err := sub.Commit(ctx, msg); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if err := sub.Extend(ctx, msg, time.Minute); !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("expected ErrInvalidReceipt after commit, got %v", err)
+	}
+}
+
+func TestBrokerSubscriberForgetsMessagesPastAckWait(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{}
+	pub := &BrokerPublisher{options: newBrokerOptions(), js: stream}
+	sub := newBrokerSubscriber(stream, WithDecoderTarget(order{}), WithAckWait(10*time.Millisecond))
+
+	for _, id := range []string{"o-1", "o-2"} {
+		if err := pub.Publish(ctx, NewMessage(order{ID: id}, "orders.created")); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	stale, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := sub.Subscribe(ctx); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if len(sub.inflight) != 1 {
+		t.Fatalf("expected the expired message to be forgotten, tracking %d", len(sub.inflight))
+	}
+	if err := sub.Commit(ctx, stale); !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("expected ErrInvalidReceipt for an expired receipt, got %v", err)
+	}
+}
+
+func TestBrokerSubscriberTerminatesOnlyPoisonPayloads(t *testing.T) {
+	ctx := context.Background()
+	stream := &fakeStream{}
+	sub := newBrokerSubscriber(stream, WithContentDecoder(broker.NewRegistry().DecoderTarget(order{})))
+
+	publish := func(data, contentType string) *fakeMsg {
+		msg := nats.NewMsg("orders.created")
+		msg.Data = []byte(data)
+		msg.Header.Set(broker.ContentTypeAttribute, contentType)
+		if _, err := stream.PublishMsg(ctx, msg); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+		return stream.pending[len(stream.pending)-1]
+	}
+	malformed := publish("{not json", broker.ContentTypeJSON)
+	unknown := publish(`{"id":"o-1"}`, "application/x-future")
+
+	if _, err := sub.Subscribe(ctx); err == nil || !malformed.termed {
+		t.Fatalf("expected malformed payload to be terminated, got %v", err)
+	}
+	if _, err := sub.Subscribe(ctx); !errors.Is(err, broker.ErrUnknownContentType) {
+		t.Fatalf("expected ErrUnknownContentType, got %v", err)
+	}
+	if unknown.termed || unknown.acked || unknown.naked {
+		t.Fatal("expected unknown content type to be left for redelivery")
+	}
+	if len(sub.inflight) != 0 {
+		t.Fatalf("expected undecodable messages not to be tracked, tracking %d", len(sub.inflight))
+	}
+}
@@ -0,0 +1,99 @@
+package natsjetstream
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// Attribute keys set by BrokerSubscriber. MessageSubject also selects the
+// subject on publish; every other attribute travels as a NATS header.
+const (
+	MessageSubject       = "X-Message-Subject"
+	MessageReceiptHandle = "X-Message-Receipt-Handle"
+	MessageReceiveCount  = "X-Message-Receive-Count"
+)
+
+type message struct {
+	data any
+	attr *attributes
+}
+
+type MessageOption func(*message)
+
+// WithHeader adds a NATS header to the message.
+func WithHeader(key, value string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(key, value)
+	}
+}
+
+func (m *message) Attributes() broker.Attributes { return m.attr }
+func (m *message) Payload() any                  { return m.data }
+
+func NewMessage(payload any, subject string, opts ...MessageOption) broker.Message {
+	message := &message{
+		data: payload,
+		attr: newAttributes(),
+	}
+
+	for _, opt := range opts {
+		opt(message)
+	}
+
+	message.attr.Add(MessageSubject, subject)
+
+	return message
+}
+
+type attributes struct {
+	mu         sync.RWMutex
+	attributes map[string][]string
+}
+
+var _ broker.Attributes = (*attributes)(nil)
+
+func newAttributes() *attributes {
+	return &attributes{
+		attributes: make(map[string][]string),
+	}
+}
+
+func (a *attributes) Add(key, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attributes[key] = append(a.attributes[key], value)
+}
+
+func (a *attributes) Get(key string) string {
+	value, _ := a.Lookup(key)
+	return value
+}
+
+func (a *attributes) Lookup(key string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	values, ok := a.attributes[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (a *attributes) Delete(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.attributes, key)
+}
+
+func (a *attributes) Values() map[string][]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make(map[string][]string, len(a.attributes))
+	for key, values := range maps.All(a.attributes) {
+		out[key] = slices.Clone(values)
+	}
+	return out
+}
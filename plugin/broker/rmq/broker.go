@@ -0,0 +1,396 @@
+package rmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+	"github.com/google/uuid"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+var (
+	// ErrNoQueue is returned by BrokerPublisher for messages without a queue.
+	ErrNoQueue = errors.New("message has no queue attribute")
+	// ErrInvalidReceipt is returned by Commit for messages that are no longer
+	// in flight, e.g. already committed or requeued after the ack timeout.
+	ErrInvalidReceipt = errors.New("receipt handle is invalid or expired")
+	ErrClosed         = errors.New("subscriber closed")
+)
+
+const defaultAckTimeout = 30 * time.Second
+
+// amqpPublisher is the subset of *rabbitmq.Publisher used by BrokerPublisher.
+type amqpPublisher interface {
+	PublishWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) error
+	Close()
+}
+
+// amqpConsumer is the subset of *rabbitmq.Consumer used by BrokerSubscriber.
+type amqpConsumer interface {
+	Run(handler rabbitmq.Handler) error
+	Close()
+}
+
+type brokerOptions struct {
+	encoder        broker.Encoder
+	decoder        broker.Decoder
+	contentDecoder broker.ContentDecoder
+	concurrency    int
+	ackTimeout     time.Duration
+}
+
+type BrokerOption func(*brokerOptions)
+
+func WithEncoder(encoder broker.Encoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.encoder = encoder
+	}
+}
+
+func WithDecoder(decoder broker.Decoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.decoder = decoder
+	}
+}
+
+// WithContentDecoder decodes each payload according to its AMQP content
+// type. It takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) BrokerOption {
+	return func(o *brokerOptions) {
+		o.contentDecoder = decoder
+	}
+}
+
+// WithDecoderTarget decodes JSON payloads into a new *T, the shape expected
+// by subscriber.Handler[T].
+func WithDecoderTarget(typeof any) BrokerOption {
+	return func(o *brokerOptions) {
+		v := reflect.TypeOf(typeof)
+
+		o.decoder = func(b []byte) (any, error) {
+			target := reflect.New(v).Interface()
+			if err := json.Unmarshal(b, target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		}
+	}
+}
+
+// WithConcurrency sets how many deliveries may be in flight at once. It
+// should match the number of workers calling Subscribe. Defaults to 1.
+func WithConcurrency(n int) BrokerOption {
+	return func(o *brokerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithAckTimeout sets how long a delivery may stay uncommitted before it is
+// requeued. Defaults to 30 seconds.
+func WithAckTimeout(timeout time.Duration) BrokerOption {
+	return func(o *brokerOptions) {
+		o.ackTimeout = timeout
+	}
+}
+
+func newBrokerOptions(opts ...BrokerOption) *brokerOptions {
+	options := &brokerOptions{
+		encoder: func(m broker.Message) ([]byte, error) {
+			return json.Marshal(m.Payload())
+		},
+		decoder: func(b []byte) (any, error) {
+			var target any
+
+			if err := json.Unmarshal(b, &target); err != nil {
+				return nil, err
+			}
+
+			return target, nil
+		},
+		concurrency: 1,
+		ackTimeout:  defaultAckTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// BrokerPublisher implements broker.Publisher on a RabbitMQ connection.
+type BrokerPublisher struct {
+	options   *brokerOptions
+	publisher amqpPublisher
+}
+
+var _ broker.Publisher = (*BrokerPublisher)(nil)
+
+func NewBrokerPublisher(conn *rabbitmq.Conn, opts ...BrokerOption) (*BrokerPublisher, error) {
+	publisher, err := rabbitmq.NewPublisher(conn, rabbitmq.WithPublisherOptionsLogging)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BrokerPublisher{
+		options:   newBrokerOptions(opts...),
+		publisher: publisher,
+	}, nil
+}
+
+// Publish implements broker.Publisher. Each message is sent persistently to
+// the exchange of its queue attribute.
+func (p *BrokerPublisher) Publish(ctx context.Context, messages ...broker.Message) error {
+	for _, m := range messages {
+		queue, ok := m.Attributes().Lookup(MessageQueue)
+		if !ok || queue == "" {
+			return ErrNoQueue
+		}
+
+		data, err := p.options.encoder(m)
+		if err != nil {
+			return err
+		}
+
+		err = p.publisher.PublishWithContext(ctx, data, []string{""},
+			rabbitmq.WithPublishOptionsContentType(contentType(m.Attributes())),
+			rabbitmq.WithPublishOptionsExchange(exchangeName(queue)),
+			rabbitmq.WithPublishOptionsPersistentDelivery,
+			rabbitmq.WithPublishOptionsHeaders(mapToHeaders(m.Attributes())),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *BrokerPublisher) Close() {
+	p.publisher.Close()
+}
+
+type delivery struct {
+	message broker.Message
+	action  chan rabbitmq.Action
+}
+
+// BrokerSubscriber implements broker.Subscriber on a RabbitMQ queue. Commit
+// acks the delivery; deliveries not committed within the ack timeout, or
+// still pending on Close, are requeued.
+type BrokerSubscriber struct {
+	options  *brokerOptions
+	queue    string
+	consumer amqpConsumer
+
+	deliveries chan *delivery
+	closed     chan struct{}
+	closeOnce  sync.Once
+
+	mu      sync.Mutex
+	pending map[string]*delivery
+}
+
+var _ broker.Subscriber = (*BrokerSubscriber)(nil)
+
+// NewBrokerSubscriber consumes queue bound to "<queue>-exchange", the same
+// topology used by Consumer and BrokerPublisher.
+func NewBrokerSubscriber(ctx context.Context, conn *rabbitmq.Conn, queue string, opts ...BrokerOption) (*BrokerSubscriber, error) {
+	options := newBrokerOptions(opts...)
+
+	consumer, err := rabbitmq.NewConsumer(conn, queue,
+		rabbitmq.WithConsumerOptionsExchangeName(exchangeName(queue)),
+		rabbitmq.WithConsumerOptionsQueueDurable,
+		rabbitmq.WithConsumerOptionsConcurrency(options.concurrency),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newBrokerSubscriber(consumer, queue, options)
+	go s.run(ctx)
+
+	return s, nil
+}
+
+func newBrokerSubscriber(consumer amqpConsumer, queue string, options *brokerOptions) *BrokerSubscriber {
+	return &BrokerSubscriber{
+		options:    options,
+		queue:      queue,
+		consumer:   consumer,
+		deliveries: make(chan *delivery),
+		closed:     make(chan struct{}),
+		pending:    make(map[string]*delivery),
+	}
+}
+
+func (s *BrokerSubscriber) run(ctx context.Context) {
+	if err := s.consumer.Run(s.handle); err != nil {
+		logger.Of(ctx).ErrorS("Rmq::BrokerSubscriber::Run", logger.WithValue("error", err))
+	}
+}
+
+// handle runs on the consumer goroutines and blocks until the delivery is
+// committed, times out or the subscriber closes. Payloads that can never be
+// decoded are discarded, or dead-lettered by the queue's policy; other decode
+// failures are requeued for a consumer that understands them.
+func (s *BrokerSubscriber) handle(d rabbitmq.Delivery) rabbitmq.Action {
+	attr := mapHeadersToAttributes(d.Headers)
+	attr.Add(MessageQueue, s.queue)
+	if d.ContentType != "" {
+		attr.Add(broker.ContentTypeAttribute, d.ContentType)
+	}
+
+	var (
+		payload any
+		err     error
+	)
+	if s.options.contentDecoder != nil {
+		payload, err = s.options.contentDecoder(attr, d.Body)
+	} else {
+		payload, err = s.options.decoder(d.Body)
+	}
+	if err != nil {
+		logger.Of(context.Background()).WarnS("Rmq::BrokerSubscriber::Decode",
+			logger.WithValue("queue", s.queue), logger.WithValue("error", err))
+		if broker.IsPoison(err) {
+			return rabbitmq.NackDiscard
+		}
+		return rabbitmq.NackRequeue
+	}
+
+	receipt := uuid.NewString()
+	attr.Add(MessageReceiptHandle, receipt)
+
+	dl := &delivery{
+		message: &message{data: payload, attr: attr},
+		action:  make(chan rabbitmq.Action, 1),
+	}
+
+	s.mu.Lock()
+	s.pending[receipt] = dl
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, receipt)
+		s.mu.Unlock()
+	}()
+
+	select {
+	case s.deliveries <- dl:
+	case <-s.closed:
+		return rabbitmq.NackRequeue
+	}
+
+	timer := time.NewTimer(s.options.ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case action := <-dl.action:
+		return action
+	case <-timer.C:
+		return rabbitmq.NackRequeue
+	case <-s.closed:
+		return rabbitmq.NackRequeue
+	}
+}
+
+func (s *BrokerSubscriber) Subscribe(ctx context.Context) (broker.Message, error) {
+	select {
+	case dl := <-s.deliveries:
+		return dl.message, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, ErrClosed
+	}
+}
+
+func (s *BrokerSubscriber) Commit(ctx context.Context, message broker.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	receipt := message.Attributes().Get(MessageReceiptHandle)
+
+	s.mu.Lock()
+	dl, ok := s.pending[receipt]
+	delete(s.pending, receipt)
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrInvalidReceipt
+	}
+
+	dl.action <- rabbitmq.Ack
+	return nil
+}
+
+// Close requeues every pending delivery and stops the consumer.
+func (s *BrokerSubscriber) Close(_ context.Context) {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.consumer.Close()
+	})
+}
+
+func contentType(attr broker.Attributes) string {
+	if value, ok := attr.Lookup(broker.ContentTypeAttribute); ok {
+		return value
+	}
+	return broker.ContentTypeJSON
+}
+
+func exchangeName(queue string) string {
+	return strings.ToLower(queue) + "-exchange"
+}
+
+func mapToHeaders(attr broker.Attributes) rabbitmq.Table {
+	headers := rabbitmq.Table{}
+	for key, values := range attr.Values() {
+		switch key {
+		case MessageQueue, MessageReceiptHandle, broker.ContentTypeAttribute:
+			continue
+		}
+		if len(values) == 1 {
+			headers[key] = values[0]
+			continue
+		}
+		list := make([]any, 0, len(values))
+		for _, v := range values {
+			list = append(list, v)
+		}
+		headers[key] = list
+	}
+	return headers
+}
+
+func mapHeadersToAttributes(headers map[string]any) *attributes {
+	attr := newAttributes()
+	for key, value := range headers {
+		switch key {
+		case MessageQueue, MessageReceiptHandle, broker.ContentTypeAttribute:
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			attr.Add(key, v)
+		case []any:
+			for _, item := range v {
+				attr.Add(key, fmt.Sprint(item))
+			}
+		default:
+			attr.Add(key, fmt.Sprint(v))
+		}
+	}
+	return attr
+}
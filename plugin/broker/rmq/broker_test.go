@@ -0,0 +1,165 @@
+package rmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+type fakePublisher struct {
+	published []rabbitmq.PublishOptions
+	bodies    [][]byte
+}
+
+func (f *fakePublisher) PublishWithContext(_ context.Context, data []byte, _ []string, optionFuncs ...func(*rabbitmq.PublishOptions)) error {
+	var options rabbitmq.PublishOptions
+	for _, fn := range optionFuncs {
+		fn(&options)
+	}
+	f.published = append(f.published, options)
+	f.bodies = append(f.bodies, data)
+	return nil
+}
+
+func (f *fakePublisher) Close() {}
+
+type fakeConsumer struct {
+	handler chan rabbitmq.Handler
+}
+
+func (f *fakeConsumer) Run(handler rabbitmq.Handler) error {
+	f.handler <- handler
+	return nil
+}
+
+func (f *fakeConsumer) Close() {}
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestBrokerPublisherMapsHeaders(t *testing.T) {
+	fake := &fakePublisher{}
+	pub := &BrokerPublisher{options: newBrokerOptions(), publisher: fake}
+
+	err := pub.Publish(context.Background(), NewMessage(order{ID: "o-1"}, "Orders", WithHeader("X-Trace-ID", "trace-1")))
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	options := fake.published[0]
+	if options.Exchange != "orders-exchange" {
+		t.Fatalf("unexpected exchange %q", options.Exchange)
+	}
+	if options.Headers["X-Trace-ID"] != "trace-1" {
+		t.Fatalf("expected trace header, got %v", options.Headers)
+	}
+	if _, ok := options.Headers[MessageQueue]; ok {
+		t.Fatal("queue attribute must not be sent as a header")
+	}
+
+	if err := pub.Publish(context.Background(), NewMessage(order{}, "")); !errors.Is(err, ErrNoQueue) {
+		t.Fatalf("expected ErrNoQueue, got %v", err)
+	}
+}
+
+func TestBrokerSubscriberCommitAndTimeout(t *testing.T) {
+	consumer := &fakeConsumer{handler: make(chan rabbitmq.Handler, 1)}
+	sub := newBrokerSubscriber(consumer, "orders",
+		newBrokerOptions(WithDecoderTarget(order{}), WithAckTimeout(20*time.Millisecond)))
+	go sub.run(context.Background())
+	handler := <-consumer.handler
+
+	deliver := func(body string) <-chan rabbitmq.Action {
+		result := make(chan rabbitmq.Action, 1)
+		go func() {
+			result <- handler(rabbitmq.Delivery{Delivery: amqp.Delivery{
+				Body:    []byte(body),
+				Headers: amqp.Table{"X-Trace-ID": "trace-1"},
+			}})
+		}()
+		return result
+	}
+
+	ctx := context.Background()
+	acked := deliver(`{"id":"o-1"}`)
+	msg, err := sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if got := msg.Payload().(*order); got.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	if got := msg.Attributes().Get("X-Trace-ID"); got != "trace-1" {
+		t.Fatalf("expected header to map to attribute, got %q", got)
+	}
+	if err := sub.Commit(ctx, msg); err != nil {
+		t.Fatalf("unexpected commit error: %v", err)
+	}
+	if action := <-acked; action != rabbitmq.Ack {
+		t.Fatalf("expected Ack, got %v", action)
+	}
+
+	requeued := deliver(`{"id":"o-2"}`)
+	msg, err = sub.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if action := <-requeued; action != rabbitmq.NackRequeue {
+		t.Fatalf("expected NackRequeue after the ack timeout, got %v", action)
+	}
+	if err := sub.Commit(ctx, msg); !errors.Is(err, ErrInvalidReceipt) {
+		t.Fatalf("expected ErrInvalidReceipt after timeout, got %v", err)
+	}
+
+	if action := <-deliver(`not json`); action != rabbitmq.NackDiscard {
+		t.Fatalf("expected NackDiscard for an undecodable body, got %v", action)
+	}
+
+	sub.Close(ctx)
+	if _, err := sub.Subscribe(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestBrokerSubscriberDiscardsOnlyPoisonPayloads(t *testing.T) {
+	consumer := &fakeConsumer{handler: make(chan rabbitmq.Handler, 1)}
+	decoder := broker.NewRegistry().DecoderTarget(order{}, broker.WithUpcaster(broker.NewUpcaster(1)))
+	sub := newBrokerSubscriber(consumer, "orders", newBrokerOptions(WithContentDecoder(decoder)))
+	go sub.run(context.Background())
+	defer sub.Close(context.Background())
+	handler := <-consumer.handler
+
+	cases := map[string]struct {
+		delivery amqp.Delivery
+		want     rabbitmq.Action
+	}{
+		"malformed payload": {
+			amqp.Delivery{ContentType: broker.ContentTypeJSON, Body: []byte(`{not json`)},
+			rabbitmq.NackDiscard,
+		},
+		"unknown content type": {
+			amqp.Delivery{ContentType: "application/x-future", Body: []byte(`{"id":"o-1"}`)},
+			rabbitmq.NackRequeue,
+		},
+		"newer schema version": {
+			amqp.Delivery{
+				ContentType: broker.ContentTypeJSON,
+				Headers:     amqp.Table{broker.SchemaVersionAttribute: "2"},
+				Body:        []byte(`{"id":"o-1"}`),
+			},
+			rabbitmq.NackRequeue,
+		},
+	}
+
+	for name, tc := range cases {
+		if action := handler(rabbitmq.Delivery{Delivery: tc.delivery}); action != tc.want {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, action)
+		}
+	}
+}
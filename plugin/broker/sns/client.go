@@ -4,12 +4,14 @@ package sns
 import (
 	"context"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aawadallak/go-core-kit/plugin/awsconfig"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
-func newClient(ctx context.Context) (*sns.Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+// NewClient returns an SNS client to pass to WithAwsClient.
+// The options override the default AWS configuration chain.
+func NewClient(ctx context.Context, opts ...awsconfig.Option) (*sns.Client, error) {
+	cfg, err := awsconfig.Load(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
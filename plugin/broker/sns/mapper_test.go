@@ -0,0 +1,88 @@
+package sns
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestMapper_FIFO(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+	topic := "arn:aws:sns:us-east-1:123456789012:orders.fifo"
+
+	if _, err := mapToPublishEntries(encoder, nil, NewMessage(order{ID: "o-1"}, topic)); !errors.Is(err, ErrNoGroupID) {
+		t.Fatalf("expected ErrNoGroupID, got %v", err)
+	}
+
+	entries, err := mapToPublishEntries(encoder, nil,
+		NewMessage(order{ID: "o-1"}, topic, WithGroupID("customer-1")),
+		NewMessage(order{ID: "o-2"}, topic, WithGroupID("customer-1"), WithDeduplicationID("dedup-2")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+
+	first, second := entries[0], entries[1]
+	if aws.ToString(first.MessageGroupId) != "customer-1" || len(aws.ToString(first.MessageDeduplicationId)) != 64 {
+		t.Fatalf("unexpected fifo fields %+v", first)
+	}
+	if aws.ToString(second.MessageDeduplicationId) != "dedup-2" {
+		t.Fatalf("unexpected deduplication id %q", aws.ToString(second.MessageDeduplicationId))
+	}
+	if _, ok := second.MessageAttributes[MessageDeduplicationID]; ok {
+		t.Fatal("deduplication id must not be sent as a message attribute")
+	}
+}
+
+func TestMapper_ChunksBatches(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+	topic := "arn:aws:sns:us-east-1:123456789012:orders"
+
+	messages := make([]broker.Message, 0, 12)
+	for i := range 12 {
+		messages = append(messages, NewMessage(order{ID: strconv.Itoa(i)}, topic))
+	}
+	entries, err := mapToPublishEntries(encoder, nil, messages...)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	batches := awsbatch.Split(entries, publishEntrySize)
+	if len(batches) != 2 || len(batches[0]) != 10 || len(batches[1]) != 2 {
+		t.Fatalf("expected batches of 10 and 2, got %d batches", len(batches))
+	}
+}
+
+func TestMapper_TypedAttributes(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+
+	msg := NewMessage(order{ID: "o-1"}, "arn:aws:sns:us-east-1:123456789012:orders")
+	broker.AddNumber(msg.Attributes(), "X-Amount", 100)
+	msg.Attributes().Add("X-Tags", "priority")
+	msg.Attributes().Add("X-Tags", "gift")
+
+	entries, err := mapToPublishEntries(encoder, nil, msg)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	sent := entries[0].MessageAttributes
+
+	if aws.ToString(sent["X-Amount"].DataType) != "Number" || aws.ToString(sent["X-Amount"].StringValue) != "100" {
+		t.Fatalf("unexpected number attribute %+v", sent["X-Amount"])
+	}
+	if aws.ToString(sent["X-Tags"].DataType) != "String.Array" || aws.ToString(sent["X-Tags"].StringValue) != `["priority","gift"]` {
+		t.Fatalf("unexpected String.Array attribute %+v", sent["X-Tags"])
+	}
+
+	broker.SetAttributeType(msg.Attributes(), "X-Amount", broker.AttributeTypeBinary)
+	if _, err := mapToPublishEntries(encoder, nil, msg); err == nil {
+		t.Fatal("expected invalid binary attribute to fail")
+	}
+}
@@ -4,9 +4,20 @@ import (
 	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
 )
 
-const MessageTopic = "topic"
+const (
+	MessageTopic = "topic"
+	// MessageContentEncoding marks messages that were base64-encoded because
+	// the payload was not valid UTF-8. Subscribers of the sqs package decode
+	// them transparently when raw message delivery is enabled.
+	MessageContentEncoding = "Content-Transfer-Encoding"
+	// MessageGroupID and MessageDeduplicationID are sent as the FIFO
+	// MessageGroupId and MessageDeduplicationId rather than as attributes.
+	MessageGroupID         = awsbatch.GroupIDAttribute
+	MessageDeduplicationID = awsbatch.DeduplicationIDAttribute
+)
 
 type message struct {
 	data      any
@@ -16,6 +27,21 @@ type message struct {
 
 type MessageOption func(*message)
 
+// WithGroupID sets the FIFO message group. Required by FIFO topics.
+func WithGroupID(id string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageGroupID, id)
+	}
+}
+
+// WithDeduplicationID sets the FIFO deduplication ID. When omitted on a FIFO
+// topic the SHA-256 of the message is used.
+func WithDeduplicationID(id string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageDeduplicationID, id)
+	}
+}
+
 func (m *message) Attributes() broker.Attributes { return m.attr }
 func (m *message) Payload() any                  { return m.data }
 
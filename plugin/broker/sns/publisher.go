@@ -2,24 +2,31 @@ package sns
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
 )
 
+// publishBatchAPI is the subset of *sns.Client used by Publisher.
+type publishBatchAPI interface {
+	PublishBatch(ctx context.Context, params *sns.PublishBatchInput, optFns ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
+}
+
 type Publisher struct {
 	options  *publisherOption
-	provider *sns.Client
+	provider publishBatchAPI
 }
 
-var _ broker.Publisher = (*Publisher)(nil)
+var _ broker.BatchPublisher = (*Publisher)(nil)
 
 func NewPublisher(ctx context.Context, opts ...PublisherOption) (*Publisher, error) {
 	options := newPublisherOption(opts...)
 
 	if options.client == nil {
-		provider, err := newClient(ctx)
+		provider, err := NewClient(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -33,24 +40,82 @@ func NewPublisher(ctx context.Context, opts ...PublisherOption) (*Publisher, err
 	}, nil
 }
 
+// Publish sends messages in as many PublishBatch requests as needed and
+// joins the errors of every failed entry.
 func (p *Publisher) Publish(ctx context.Context, messages ...broker.Message) error {
-	if len(messages) > 10 {
-		return ErrSizeLimit
-	}
-
-	req, err := mapToPublishInput(p.options.encoder, messages...)
+	result, err := p.PublishBatch(ctx, messages...)
 	if err != nil {
 		return err
 	}
 
-	result, err := p.provider.PublishBatch(ctx, req)
+	return result.Err()
+}
+
+// PublishBatch sends messages in as many PublishBatch requests as needed and
+// reports the outcome of each. All messages are encoded before anything is
+// sent. With WithRetry, transient failures are sent again.
+func (p *Publisher) PublishBatch(ctx context.Context, messages ...broker.Message) (*broker.PublishResult, error) {
+	var offload offloader
+	if p.options.claimCheck != nil {
+		offload = func(m broker.Message, payload []byte) ([]byte, error) {
+			return p.options.claimCheck.Offload(ctx, m, payload, broker.WithEncodedSize(awsbatch.BodySize))
+		}
+	}
+
+	entries, err := mapToPublishEntries(p.options.encoder, offload, messages...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	result := broker.NewPublishResult(messages)
+	if len(messages) == 0 {
+		return result, nil
 	}
+	topic := messages[0].Attributes().Get(MessageTopic)
+
+	awsbatch.Retry(ctx, entries, p.options.maxAttempts, p.options.backoff,
+		func(ctx context.Context, entries []types.PublishBatchRequestEntry) []types.PublishBatchRequestEntry {
+			return p.send(ctx, topic, entries, result)
+		})
+
+	return result, nil
+}
 
-	for _, r := range result.Failed {
-		return fmt.Errorf("failed to publish message: %s", *r.Message)
+// send records the outcome of entries in result and returns the failed
+// entries worth retrying, see awsbatch.Send.
+func (p *Publisher) send(ctx context.Context, topic string, entries []types.PublishBatchRequestEntry, result *broker.PublishResult) []types.PublishBatchRequestEntry {
+	id := func(entry types.PublishBatchRequestEntry) *string { return entry.Id }
+	return awsbatch.Send(ctx, awsbatch.Split(entries, publishEntrySize), id, func(ctx context.Context, batch []types.PublishBatchRequestEntry) (awsbatch.Response, error) {
+		output, err := p.provider.PublishBatch(ctx, &sns.PublishBatchInput{TopicArn: aws.String(topic), PublishBatchRequestEntries: batch})
+		if err != nil {
+			return awsbatch.Response{}, err
+		}
+		return mapFromBatchOutput(output), nil
+	}, result)
+}
+
+func newPublishMessageError(cause types.BatchResultErrorEntry) error {
+	return &ErrSendMessage{
+		Code:        aws.ToString(cause.Code),
+		Message:     aws.ToString(cause.Message),
+		SenderFault: cause.SenderFault,
 	}
+}
 
-	return nil
+func mapFromBatchOutput(output *sns.PublishBatchOutput) awsbatch.Response {
+	var res awsbatch.Response
+	for _, r := range output.Successful {
+		res.Successful = append(res.Successful, awsbatch.Success{
+			ID:        aws.ToString(r.Id),
+			MessageID: aws.ToString(r.MessageId),
+		})
+	}
+	for _, r := range output.Failed {
+		res.Failed = append(res.Failed, awsbatch.Failure{
+			ID:          aws.ToString(r.Id),
+			Err:         newPublishMessageError(r),
+			SenderFault: r.SenderFault,
+		})
+	}
+	return res
 }
@@ -1,36 +1,51 @@
 package sns
 
 import (
+	"fmt"
+
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
-	"github.com/google/uuid"
 )
 
-func mapToPublishInput(encoder broker.Encoder, messages ...broker.Message) (*sns.PublishBatchInput, error) {
-	topic := messages[0].Attributes().Get(MessageTopic)
-
-	entries, err := mapToPublishEntries(encoder, messages...)
-	if err != nil {
-		return nil, err
+// publishEntrySize approximates how an entry counts towards the batch size
+// limit: its message plus attribute names, types and values.
+func publishEntrySize(entry types.PublishBatchRequestEntry) int {
+	size := len(aws.ToString(entry.Message))
+	for name, value := range entry.MessageAttributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue)) + len(value.BinaryValue)
 	}
-
-	return &sns.PublishBatchInput{
-		TopicArn:                   aws.String(topic),
-		PublishBatchRequestEntries: entries,
-	}, nil
+	return size
 }
 
-func mapToPublishEntries(encoder broker.Encoder, messages ...broker.Message) ([]types.PublishBatchRequestEntry, error) {
+// mapToPublishEntries encodes messages into entries whose Id is the
+// index of their message, so batch results can be traced back to it. A
+// non-nil offload replaces oversized payloads, as ClaimCheck.Offload does;
+// FIFO deduplication IDs are still derived from the payload itself.
+func mapToPublishEntries(encoder broker.Encoder, offload offloader, messages ...broker.Message) ([]types.PublishBatchRequestEntry, error) {
 	entries := make([]types.PublishBatchRequestEntry, 0, len(messages))
-	for _, message := range messages {
+	for i, message := range messages {
 		payload, err := encoder(message)
 		if err != nil {
 			return nil, err
 		}
 
-		entry := mapToPublishEntry(string(payload), message.Attributes())
+		body := payload
+		if offload != nil {
+			if body, err = offload(message, payload); err != nil {
+				return nil, err
+			}
+		}
+
+		entry, err := mapToPublishEntry(body, message.Attributes())
+		if err != nil {
+			return nil, err
+		}
+		if err := mapFIFO(&entry, payload, message.Attributes()); err != nil {
+			return nil, err
+		}
+		entry.Id = awsbatch.EntryID(i)
 
 		entries = append(entries, entry)
 	}
@@ -38,21 +53,51 @@ func mapToPublishEntries(encoder broker.Encoder, messages ...broker.Message) ([]
 	return entries, nil
 }
 
-func mapToPublishEntry(body string, attributes broker.Attributes) types.PublishBatchRequestEntry {
+func mapToPublishEntry(payload []byte, attributes broker.Attributes) (types.PublishBatchRequestEntry, error) {
+	body, encoding := awsbatch.EncodeBody(payload)
+
 	res := types.PublishBatchRequestEntry{
-		Id:                aws.String(uuid.NewString()),
 		Message:           aws.String(body),
 		MessageAttributes: make(map[string]types.MessageAttributeValue),
 	}
 
 	for k, val := range attributes.Values() {
-		for _, v := range val {
-			res.MessageAttributes[k] = types.MessageAttributeValue{
-				DataType:    aws.String("String"),
-				StringValue: aws.String(v),
-			}
+		if k == MessageGroupID || k == MessageDeduplicationID || len(val) == 0 {
+			continue
+		}
+
+		value, err := awsbatch.AttributeValueOf(broker.AttributeTypeOf(attributes, k), val)
+		if err != nil {
+			return res, fmt.Errorf("invalid attribute %s: %w", k, err)
+		}
+		res.MessageAttributes[k] = types.MessageAttributeValue{
+			DataType:    aws.String(value.DataType),
+			StringValue: value.StringValue,
+			BinaryValue: value.BinaryValue,
+		}
+	}
+
+	if encoding != "" {
+		res.MessageAttributes[MessageContentEncoding] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(encoding),
 		}
 	}
 
-	return res
+	return res, nil
+}
+
+// offloader replaces an oversized payload by a reference to where it was
+// stored.
+type offloader func(message broker.Message, payload []byte) ([]byte, error)
+
+// mapFIFO sets the group and deduplication IDs, see awsbatch.FIFO. FIFO
+// topics must have a group ID.
+func mapFIFO(entry *types.PublishBatchRequestEntry, payload []byte, attributes broker.Attributes) error {
+	destination := attributes.Get(MessageTopic)
+	entry.MessageGroupId, entry.MessageDeduplicationId = awsbatch.FIFO(destination, attributes, payload)
+	if awsbatch.IsFIFO(destination) && entry.MessageGroupId == nil {
+		return ErrNoGroupID
+	}
+	return nil
 }
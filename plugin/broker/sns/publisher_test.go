@@ -0,0 +1,180 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// fakePublisher fails whole requests with callErrs, in order, and then
+// entries listed in failures until their count runs out.
+type fakePublisher struct {
+	calls    int
+	topics   []string
+	sent     []types.PublishBatchRequestEntry
+	callErrs []error
+	failures map[string]int
+}
+
+func (f *fakePublisher) PublishBatch(_ context.Context, in *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	f.calls++
+	f.topics = append(f.topics, aws.ToString(in.TopicArn))
+	if len(f.callErrs) > 0 {
+		err := f.callErrs[0]
+		f.callErrs = f.callErrs[1:]
+		return nil, err
+	}
+
+	out := &sns.PublishBatchOutput{}
+	f.sent = append(f.sent, in.PublishBatchRequestEntries...)
+	for _, entry := range in.PublishBatchRequestEntries {
+		id := aws.ToString(entry.Id)
+		if f.failures[id] > 0 {
+			f.failures[id]--
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{
+				Id:      entry.Id,
+				Code:    aws.String("Throttled"),
+				Message: aws.String("slow down"),
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, types.PublishBatchResultEntry{
+			Id:        entry.Id,
+			MessageId: aws.String("msg-" + id),
+		})
+	}
+	return out, nil
+}
+
+func TestPublisher_ReportsAllFailuresAndRetries(t *testing.T) {
+	topic := "arn:aws:sns:us-east-1:123456789012:orders"
+	messages := make([]broker.Message, 0, 3)
+	for i := range 3 {
+		messages = append(messages, NewMessage(order{ID: strconv.Itoa(i)}, topic))
+	}
+
+	fake := &fakePublisher{failures: map[string]int{"0": 1, "2": 1}}
+	result, err := (&Publisher{options: newPublisherOption(), provider: fake}).PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if failed := result.Failed(); len(failed) != 2 || failed[0].Message != messages[0] || failed[1].Message != messages[2] {
+		t.Fatalf("expected both failures to be reported, got %+v", failed)
+	}
+	if got := result.Failed()[1].Err.Error(); got != "status=Throttled message=slow down" {
+		t.Fatalf("unexpected error message %q", got)
+	}
+
+	fake = &fakePublisher{failures: map[string]int{"0": 1, "2": 1}}
+	pub := &Publisher{options: newPublisherOption(WithRetry(2, time.Millisecond)), provider: fake}
+	if err := pub.Publish(context.Background(), messages...); err != nil {
+		t.Fatalf("expected retry to publish failed entries, got %v", err)
+	}
+	if fake.calls != 2 {
+		t.Fatalf("expected one retry request, got %d calls", fake.calls)
+	}
+}
+
+func TestPublisher_RetriesTransientRequestFailures(t *testing.T) {
+	topic := "arn:aws:sns:us-east-1:123456789012:orders"
+	messages := []broker.Message{
+		NewMessage(order{ID: "o-0"}, topic),
+		NewMessage(order{ID: "o-1"}, topic),
+	}
+
+	fake := &fakePublisher{callErrs: []error{&smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+		Err:      errors.New("service unavailable"),
+	}}}
+	pub := &Publisher{options: newPublisherOption(WithRetry(2, time.Millisecond)), provider: fake}
+	result, err := pub.PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if fake.calls != 2 {
+		t.Fatalf("expected the failed request to be sent again, got %d calls", fake.calls)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("expected every entry to succeed on retry, got %v", err)
+	}
+
+	fake = &fakePublisher{callErrs: []error{context.Canceled}}
+	pub = &Publisher{options: newPublisherOption(WithRetry(3, time.Millisecond)), provider: fake}
+	result, err = pub.PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if fake.calls != 1 || len(result.Failed()) != 2 {
+		t.Fatalf("expected a canceled request not to be retried, got %d calls", fake.calls)
+	}
+}
+
+type blobStore map[string][]byte
+
+func (s blobStore) Put(_ context.Context, key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func (s blobStore) Get(_ context.Context, key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func TestPublisher_ClaimCheckMeasuresEncodedBody(t *testing.T) {
+	binary := func(broker.Message) ([]byte, error) { return bytes.Repeat([]byte{0xff}, 900), nil }
+	store := blobStore{}
+	fake := &fakePublisher{}
+	pub := &Publisher{
+		options:  newPublisherOption(WithEncoder(binary), WithClaimCheck(broker.NewClaimCheck(store, 1024))),
+		provider: fake,
+	}
+
+	msg := NewMessage(order{ID: "o-1"}, "arn:aws:sns:us-east-1:123456789012:orders")
+	if err := pub.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if _, ok := msg.Attributes().Lookup(broker.ClaimCheckAttribute); !ok || len(store) != 1 {
+		t.Fatal("expected a binary payload whose base64 body exceeds the threshold to be offloaded")
+	}
+}
+
+func TestPublisher_ClaimCheckDeduplicatesOnPayload(t *testing.T) {
+	large := func(broker.Message) ([]byte, error) { return bytes.Repeat([]byte("a"), 2048), nil }
+	store := blobStore{}
+	fake := &fakePublisher{}
+	pub := &Publisher{
+		options:  newPublisherOption(WithEncoder(large), WithClaimCheck(broker.NewClaimCheck(store, 1024))),
+		provider: fake,
+	}
+
+	topic := "arn:aws:sns:us-east-1:123456789012:orders.fifo"
+	for range 2 {
+		if err := pub.Publish(context.Background(), NewMessage(order{ID: "o-1"}, topic, WithGroupID("customer-1"))); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	if len(fake.sent) != 2 || len(store) != 2 {
+		t.Fatalf("expected both payloads offloaded and sent, got %d sent and %d stored", len(fake.sent), len(store))
+	}
+	if fake.topics[1] != topic {
+		t.Fatalf("unexpected topic %q", fake.topics[1])
+	}
+	first, second := fake.sent[0], fake.sent[1]
+	if aws.ToString(first.Message) == aws.ToString(second.Message) {
+		t.Fatal("expected distinct claim-check keys")
+	}
+	if aws.ToString(first.MessageDeduplicationId) != aws.ToString(second.MessageDeduplicationId) {
+		t.Fatal("expected the same payload to share a deduplication id")
+	}
+}
@@ -4,12 +4,14 @@ package sqs
 import (
 	"context"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aawadallak/go-core-kit/plugin/awsconfig"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
-func newClient(ctx context.Context) (*sqs.Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+// NewClient returns an SQS client to pass to WithAwsClient and WithPublisherAwsClient.
+// The options override the default AWS configuration chain.
+func NewClient(ctx context.Context, opts ...awsconfig.Option) (*sqs.Client, error) {
+	cfg, err := awsconfig.Load(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,25 @@
+package sqs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/plugin/awsconfig"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestNewClient_UsesAwsOptions(t *testing.T) {
+	client, err := NewClient(context.Background(),
+		awsconfig.WithConfig(aws.Config{Region: "us-east-1"}),
+		awsconfig.WithRegion("eu-west-1"),
+		awsconfig.WithEndpoint("http://localhost:4566"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+
+	options := client.Options()
+	if options.Region != "eu-west-1" || aws.ToString(options.BaseEndpoint) != "http://localhost:4566" {
+		t.Fatalf("unexpected client options region=%q endpoint=%q", options.Region, aws.ToString(options.BaseEndpoint))
+	}
+}
@@ -2,21 +2,41 @@ package sqs
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/aawadallak/go-core-kit/common"
 )
 
-var ErrSizeLimit = errors.New("the size limit to publish is 10 messages")
+var (
+	// Deprecated: Publish splits messages into batches and no longer returns it.
+	ErrSizeLimit = errors.New("the size limit to publish is 10 messages")
+	ErrNoGroupID = errors.New("fifo queue requires a message group id")
+)
 
+// ErrSendMessage is the error of a batch entry SQS did not accept.
 type ErrSendMessage struct {
 	Code    string
 	Message string
+	// SenderFault reports that the entry itself is invalid and retrying it
+	// will not help.
+	SenderFault bool
 }
 
 var errSendMessage = &ErrSendMessage{}
 
 func (e *ErrSendMessage) Error() string {
-	return "status=%s message=%s"
+	return fmt.Sprintf("status=%s message=%s", e.Code, e.Message)
 }
 
 func (e *ErrSendMessage) Is(target error) bool {
 	return errors.Is(target, errSendMessage)
 }
+
+// FailureMode implements common.FailureModeError: sender faults are not
+// recoverable.
+func (e *ErrSendMessage) FailureMode() common.FailureMode {
+	if e.SenderFault {
+		return common.FailureModeNonRecoverable
+	}
+	return common.FailureModeRecoverable
+}
@@ -0,0 +1,278 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestMapper_BinaryBodyRoundTrip(t *testing.T) {
+	registry := broker.NewRegistry()
+	encoder := registry.Encoder(broker.ContentTypeJSON + "+gzip")
+
+	msg := NewMessage(order{ID: "o-1"}, "https://sqs/orders")
+	msg.Attributes().Add("X-Trace-ID", "trace-1")
+
+	entries, err := mapToSendMessageEntries(encoder, nil, msg)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	entry := entries[0]
+	if got := aws.ToString(entry.MessageAttributes[MessageContentEncoding].StringValue); got != contentEncodingBase64 {
+		t.Fatalf("expected gzip body to be base64-encoded, got encoding %q", got)
+	}
+
+	received := types.Message{
+		MessageId:         aws.String("id-1"),
+		ReceiptHandle:     aws.String("receipt-1"),
+		Body:              entry.MessageBody,
+		MessageAttributes: entry.MessageAttributes,
+	}
+	options := newSubscriberOption(WithContentDecoder(registry.DecoderTarget(order{})))
+
+	got, err := mapProviderToMessage(context.Background(), options, "https://sqs/orders", received)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if payload := got.Payload().(*order); payload.ID != "o-1" {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+	if got.Attributes().Get("X-Trace-ID") != "trace-1" {
+		t.Fatalf("expected message attributes to be mapped, got %v", got.Attributes().Values())
+	}
+	if values := got.Attributes().Values()[MessageQueue]; len(values) != 1 {
+		t.Fatalf("expected a single queue attribute, got %v", values)
+	}
+}
+
+func TestMapper_BatchInputs(t *testing.T) {
+	for limit, want := range map[int]int32{0: 1, 4: 4, 25: maxBatchSize} {
+		if got := mapToReceiveMessageInput("https://sqs/orders", limit).MaxNumberOfMessages; got != want {
+			t.Fatalf("limit %d: expected MaxNumberOfMessages %d, got %d", limit, want, got)
+		}
+	}
+
+	messages := make([]broker.Message, 0, 2)
+	for _, receipt := range []string{"receipt-1", "receipt-2"} {
+		msg := NewMessage(order{}, "https://sqs/orders")
+		msg.Attributes().Add(MessageReceiptHandle, receipt)
+		messages = append(messages, msg)
+	}
+
+	input := mapToCommitMessageBatchInput("https://sqs/orders", messages)
+	if len(input.Entries) != 2 || aws.ToString(input.Entries[1].ReceiptHandle) != "receipt-2" {
+		t.Fatalf("unexpected delete entries %+v", input.Entries)
+	}
+	if aws.ToString(input.Entries[0].Id) == aws.ToString(input.Entries[1].Id) {
+		t.Fatal("expected unique entry ids")
+	}
+}
+
+func TestMapper_ChangeVisibilityInput(t *testing.T) {
+	msg := NewMessage(order{}, "https://sqs/orders")
+	msg.Attributes().Add(MessageReceiptHandle, "receipt-1")
+
+	for d, want := range map[time.Duration]int32{
+		1500 * time.Millisecond: 2,
+		time.Minute:             60,
+		24 * time.Hour:          maxVisibilityTimeout,
+	} {
+		input := mapToChangeVisibilityInput("https://sqs/orders", msg, d)
+		if input.VisibilityTimeout != want || aws.ToString(input.ReceiptHandle) != "receipt-1" {
+			t.Fatalf("%s: unexpected input %+v", d, input)
+		}
+	}
+}
+
+func TestMapper_FIFO(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+	queue := "https://sqs/orders.fifo"
+
+	if _, err := mapToSendMessageEntries(encoder, nil, NewMessage(order{ID: "o-1"}, queue)); !errors.Is(err, ErrNoGroupID) {
+		t.Fatalf("expected ErrNoGroupID, got %v", err)
+	}
+
+	entries, err := mapToSendMessageEntries(encoder, nil,
+		NewMessage(order{ID: "o-1"}, queue, WithGroupID("customer-1")),
+		NewMessage(order{ID: "o-1"}, queue, WithGroupID("customer-1")),
+		NewMessage(order{ID: "o-2"}, queue, WithGroupID("customer-1"), WithDeduplicationID("dedup-2")),
+	)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+
+	first, second, third := entries[0], entries[1], entries[2]
+	if aws.ToString(first.MessageGroupId) != "customer-1" {
+		t.Fatalf("unexpected group id %q", aws.ToString(first.MessageGroupId))
+	}
+	if first.MessageDeduplicationId == nil || aws.ToString(first.MessageDeduplicationId) != aws.ToString(second.MessageDeduplicationId) {
+		t.Fatal("expected equal bodies to share a content-based deduplication id")
+	}
+	if aws.ToString(third.MessageDeduplicationId) != "dedup-2" {
+		t.Fatalf("unexpected deduplication id %q", aws.ToString(third.MessageDeduplicationId))
+	}
+	if _, ok := first.MessageAttributes[MessageGroupID]; ok {
+		t.Fatal("group id must not be sent as a message attribute")
+	}
+
+	standard, err := mapToSendMessageEntries(encoder, nil, NewMessage(order{ID: "o-1"}, "https://sqs/orders"))
+	if err != nil || standard[0].MessageDeduplicationId != nil {
+		t.Fatalf("expected standard queue without deduplication id, got %+v, %v", standard[0], err)
+	}
+}
+
+type memoryStore map[string][]byte
+
+func (s memoryStore) Put(_ context.Context, key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func (s memoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	return s[key], nil
+}
+
+func TestMapper_ChunksBatches(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+
+	messages := make([]broker.Message, 0, 23)
+	for i := range 23 {
+		messages = append(messages, NewMessage(order{ID: strconv.Itoa(i)}, "https://sqs/orders"))
+	}
+	entries, err := mapToSendMessageEntries(encoder, nil, messages...)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	inputs := awsbatch.Split(entries, sendMessageEntrySize)
+	if len(inputs) != 3 || len(inputs[0]) != 10 || len(inputs[2]) != 3 {
+		t.Fatalf("expected batches of 10, 10 and 3, got %d batches", len(inputs))
+	}
+
+	large := strings.Repeat("x", 100*1024)
+	entries, err = mapToSendMessageEntries(encoder, nil,
+		NewMessage(order{ID: large}, "https://sqs/orders"),
+		NewMessage(order{ID: large}, "https://sqs/orders"),
+		NewMessage(order{ID: large}, "https://sqs/orders"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	inputs = awsbatch.Split(entries, sendMessageEntrySize)
+	if len(inputs) != 2 || len(inputs[0]) != 2 {
+		t.Fatalf("expected batches split by size, got %d batches", len(inputs))
+	}
+}
+
+func TestMapper_ClaimCheckRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := memoryStore{}
+	claimCheck := broker.NewClaimCheck(store, 1024)
+	encoder := claimCheck.Encoder(ctx, broker.NewRegistry().Encoder(broker.ContentTypeJSON))
+
+	large := strings.Repeat("x", 2048)
+	entries, err := mapToSendMessageEntries(encoder, nil,
+		NewMessage(order{ID: large}, "https://sqs/orders"),
+		NewMessage(order{ID: "small"}, "https://sqs/orders"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	offloaded, inline := entries[0], entries[1]
+	if len(store) != 1 || len(aws.ToString(offloaded.MessageBody)) > 1024 {
+		t.Fatalf("expected large payload to be offloaded, body %q", aws.ToString(offloaded.MessageBody))
+	}
+	if _, ok := inline.MessageAttributes[broker.ClaimCheckAttribute]; ok {
+		t.Fatal("small payload must be sent inline")
+	}
+
+	options := newSubscriberOption(WithDecoderTarget(order{}), WithRehydration(claimCheck))
+	got, err := mapProviderToMessage(ctx, options, "https://sqs/orders", types.Message{
+		MessageId:         aws.String("id-1"),
+		ReceiptHandle:     aws.String("receipt-1"),
+		Body:              offloaded.MessageBody,
+		MessageAttributes: offloaded.MessageAttributes,
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if got.Payload().(*order).ID != large {
+		t.Fatal("expected offloaded payload to be rehydrated")
+	}
+}
+
+func TestMapper_TypedAttributesRoundTrip(t *testing.T) {
+	encoder := broker.NewRegistry().Encoder(broker.ContentTypeJSON)
+
+	msg := NewMessage(order{ID: "o-1"}, "https://sqs/orders")
+	broker.AddNumber(msg.Attributes(), "X-Amount", 42.5)
+	broker.AddBinary(msg.Attributes(), "X-Signature", []byte{0xde, 0xad})
+	msg.Attributes().Add("X-Tags", "priority")
+	msg.Attributes().Add("X-Tags", "gift")
+	broker.AddStringArray(msg.Attributes(), "X-Regions", "eu")
+
+	entries, err := mapToSendMessageEntries(encoder, nil, msg)
+	if err != nil {
+		t.Fatalf("unexpected map error: %v", err)
+	}
+	sent := entries[0].MessageAttributes
+
+	for key, want := range map[string]string{
+		"X-Amount":    "Number",
+		"X-Signature": "Binary",
+		"X-Tags":      "String.Array",
+		"X-Regions":   "String.Array",
+		MessageQueue:  "String",
+	} {
+		if got := aws.ToString(sent[key].DataType); got != want {
+			t.Fatalf("%s: expected data type %s, got %s", key, want, got)
+		}
+	}
+	if got := aws.ToString(sent["X-Tags"].StringValue); got != `["priority","gift"]` {
+		t.Fatalf("unexpected String.Array value %s", got)
+	}
+
+	sent["X-Mixed"] = types.MessageAttributeValue{DataType: aws.String("String.Array"), StringValue: aws.String(`["a",1,true]`)}
+	sent["X-Count"] = types.MessageAttributeValue{DataType: aws.String("Number.int"), StringValue: aws.String("7")}
+
+	got, err := mapProviderToMessage(context.Background(), newSubscriberOption(WithDecoderTarget(order{})), "https://sqs/orders", types.Message{
+		MessageId:         aws.String("id-1"),
+		ReceiptHandle:     aws.String("receipt-1"),
+		Body:              entries[0].MessageBody,
+		MessageAttributes: sent,
+	})
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	attrs := got.Attributes()
+	if values := attrs.Values()["X-Tags"]; len(values) != 2 || values[1] != "gift" {
+		t.Fatalf("expected both tags, got %v", values)
+	}
+	if values := attrs.Values()["X-Mixed"]; len(values) != 3 || values[1] != "1" || values[2] != "true" {
+		t.Fatalf("unexpected mixed array %v", values)
+	}
+	if attrs.Get("X-Amount") != "42.5" || broker.AttributeTypeOf(attrs, "X-Amount") != broker.AttributeTypeNumber {
+		t.Fatalf("unexpected number attribute %q", attrs.Get("X-Amount"))
+	}
+	if broker.AttributeTypeOf(attrs, "X-Count") != broker.AttributeTypeNumber {
+		t.Fatal("expected custom Number type to be recognised")
+	}
+	if broker.AttributeTypeOf(attrs, "X-Regions") != broker.AttributeTypeStringArray || attrs.Get("X-Regions") != "eu" {
+		t.Fatal("expected single-valued String.Array to keep its type")
+	}
+	if attrs.Get("X-Signature") != "3q0=" || broker.AttributeTypeOf(attrs, "X-Signature") != broker.AttributeTypeBinary {
+		t.Fatalf("unexpected binary attribute %q", attrs.Get("X-Signature"))
+	}
+}
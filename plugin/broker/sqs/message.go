@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
 )
 
 const (
@@ -13,8 +14,18 @@ const (
 	MessageID             = "X-Message-ID"
 	MessageQueue          = "X-Message-Queue"
 	MessageDelaySecond    = "X-Message-Delay-Second"
+	MessageReceiveCount   = "X-Message-Receive-Count"
+	// MessageGroupID and MessageDeduplicationID are sent as the FIFO
+	// MessageGroupId and MessageDeduplicationId rather than as attributes.
+	MessageGroupID         = awsbatch.GroupIDAttribute
+	MessageDeduplicationID = awsbatch.DeduplicationIDAttribute
+	// MessageContentEncoding marks bodies that were base64-encoded because
+	// the payload was not valid UTF-8.
+	MessageContentEncoding = "Content-Transfer-Encoding"
 )
 
+const contentEncodingBase64 = awsbatch.ContentEncodingBase64
+
 type message struct {
 	data      any
 	attr      *attributes
@@ -29,6 +40,22 @@ func WithDelaySecond(delay int) MessageOption {
 	}
 }
 
+// WithGroupID sets the FIFO message group; messages of a group are delivered
+// in order. Required by FIFO queues.
+func WithGroupID(id string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageGroupID, id)
+	}
+}
+
+// WithDeduplicationID sets the FIFO deduplication ID. When omitted on a FIFO
+// queue the SHA-256 of the body is used.
+func WithDeduplicationID(id string) MessageOption {
+	return func(m *message) {
+		m.attr.Add(MessageDeduplicationID, id)
+	}
+}
+
 func (m *message) Attributes() broker.Attributes { return m.attr }
 func (m *message) Payload() any                  { return m.data }
 
@@ -9,13 +9,15 @@ import (
 type attributes struct {
 	sync.RWMutex
 	attributes map[string][]string
+	types      map[string]broker.AttributeType
 }
 
-var _ broker.Attributes = (*attributes)(nil)
+var _ broker.TypedAttributes = (*attributes)(nil)
 
 func newAttributes() *attributes {
 	return &attributes{
 		attributes: make(map[string][]string),
+		types:      make(map[string]broker.AttributeType),
 	}
 }
 
@@ -41,8 +43,17 @@ func (a *attributes) Lookup(key string) (string, bool) {
 
 func (a *attributes) Delete(key string) {
 	delete(a.attributes, key)
+	delete(a.types, key)
 }
 
 func (a *attributes) Values() map[string][]string {
 	return a.attributes
 }
+
+func (a *attributes) SetType(key string, t broker.AttributeType) {
+	a.types[key] = t
+}
+
+func (a *attributes) Type(key string) broker.AttributeType {
+	return a.types[key]
+}
@@ -2,60 +2,120 @@ package sqs
 
 import (
 	"context"
-	"errors"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
+// sendMessageBatchAPI is the subset of *sqs.Client used by Publisher.
+type sendMessageBatchAPI interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
 type Publisher struct {
 	options  *publisherOption
-	provider *sqs.Client
+	provider sendMessageBatchAPI
 }
 
-var _ broker.Publisher = (*Publisher)(nil)
+var _ broker.BatchPublisher = (*Publisher)(nil)
 
+// Publish sends messages in as many SendMessageBatch requests as needed and
+// joins the errors of every failed entry.
 func (p *Publisher) Publish(ctx context.Context, messages ...broker.Message) error {
-	if len(messages) > 10 {
-		return ErrSizeLimit
-	}
-
-	req, err := mapToSendMessageInput(p.options.encoder, messages...)
+	result, err := p.PublishBatch(ctx, messages...)
 	if err != nil {
 		return err
 	}
 
-	result, err := p.provider.SendMessageBatch(ctx, req)
+	return result.Err()
+}
+
+// PublishBatch sends messages in as many SendMessageBatch requests as needed
+// and reports the outcome of each. All messages are encoded before anything
+// is sent. With WithRetry, transient failures are sent again.
+func (p *Publisher) PublishBatch(ctx context.Context, messages ...broker.Message) (*broker.PublishResult, error) {
+	var offload offloader
+	if p.options.claimCheck != nil {
+		offload = func(m broker.Message, payload []byte) ([]byte, error) {
+			return p.options.claimCheck.Offload(ctx, m, payload, broker.WithEncodedSize(awsbatch.BodySize))
+		}
+	}
+
+	entries, err := mapToSendMessageEntries(p.options.encoder, offload, messages...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var publishErr []error
-	for _, r := range result.Failed {
-		publishErr = append(publishErr, newPublishMessageError(r))
+	result := broker.NewPublishResult(messages)
+	if len(messages) == 0 {
+		return result, nil
 	}
+	queue := messages[0].Attributes().Get(MessageQueue)
 
-	return errors.Join(publishErr...)
+	awsbatch.Retry(ctx, entries, p.options.maxAttempts, p.options.backoff,
+		func(ctx context.Context, entries []types.SendMessageBatchRequestEntry) []types.SendMessageBatchRequestEntry {
+			return p.send(ctx, queue, entries, result)
+		})
+
+	return result, nil
+}
+
+// send records the outcome of entries in result and returns the failed
+// entries worth retrying, see awsbatch.Send.
+func (p *Publisher) send(ctx context.Context, queue string, entries []types.SendMessageBatchRequestEntry, result *broker.PublishResult) []types.SendMessageBatchRequestEntry {
+	id := func(entry types.SendMessageBatchRequestEntry) *string { return entry.Id }
+	return awsbatch.Send(ctx, awsbatch.Split(entries, sendMessageEntrySize), id, func(ctx context.Context, batch []types.SendMessageBatchRequestEntry) (awsbatch.Response, error) {
+		output, err := p.provider.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{QueueUrl: aws.String(queue), Entries: batch})
+		if err != nil {
+			return awsbatch.Response{}, err
+		}
+		return mapFromBatchOutput(output), nil
+	}, result)
 }
 
 func NewPublisher(ctx context.Context, opts ...PublisherOption) (*Publisher, error) {
 	options := newPublisherOption(opts...)
 
-	provider, err := newClient(ctx)
-	if err != nil {
-		return nil, err
+	if options.client == nil {
+		provider, err := NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		options.client = provider
 	}
 
 	return &Publisher{
 		options:  options,
-		provider: provider,
+		provider: options.client,
 	}, nil
 }
 
 func newPublishMessageError(cause types.BatchResultErrorEntry) error {
 	return &ErrSendMessage{
-		Code:    *cause.Code,
-		Message: *cause.Message,
+		Code:        aws.ToString(cause.Code),
+		Message:     aws.ToString(cause.Message),
+		SenderFault: cause.SenderFault,
+	}
+}
+
+func mapFromBatchOutput(output *sqs.SendMessageBatchOutput) awsbatch.Response {
+	var res awsbatch.Response
+	for _, r := range output.Successful {
+		res.Successful = append(res.Successful, awsbatch.Success{
+			ID:        aws.ToString(r.Id),
+			MessageID: aws.ToString(r.MessageId),
+		})
+	}
+	for _, r := range output.Failed {
+		res.Failed = append(res.Failed, awsbatch.Failure{
+			ID:          aws.ToString(r.Id),
+			Err:         newPublishMessageError(r),
+			SenderFault: r.SenderFault,
+		})
 	}
+	return res
 }
@@ -1,41 +1,52 @@
 package sqs
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
-	"github.com/google/uuid"
 )
 
-func mapToSendMessageInput(encoder broker.Encoder, messages ...broker.Message) (*sqs.SendMessageBatchInput, error) {
-	queue := messages[0].Attributes().Get(MessageQueue)
-
-	entries, err := mapToSendMessageEntries(encoder, messages...)
-	if err != nil {
-		return nil, err
+// sendMessageEntrySize approximates how an entry counts towards the batch
+// size limit: its body plus attribute names, types and values.
+func sendMessageEntrySize(entry types.SendMessageBatchRequestEntry) int {
+	size := len(aws.ToString(entry.MessageBody))
+	for name, value := range entry.MessageAttributes {
+		size += len(name) + len(aws.ToString(value.DataType)) + len(aws.ToString(value.StringValue)) + len(value.BinaryValue)
 	}
-
-	return &sqs.SendMessageBatchInput{
-		Entries:  entries,
-		QueueUrl: aws.String(queue),
-	}, nil
+	return size
 }
 
-func mapToSendMessageEntries(encoder broker.Encoder, messages ...broker.Message) ([]types.SendMessageBatchRequestEntry, error) {
+// mapToSendMessageEntries encodes messages into entries whose Id is the
+// index of their message, so batch results can be traced back to it. A
+// non-nil offload replaces oversized payloads, as ClaimCheck.Offload does;
+// FIFO deduplication IDs are still derived from the payload itself.
+func mapToSendMessageEntries(encoder broker.Encoder, offload offloader, messages ...broker.Message) ([]types.SendMessageBatchRequestEntry, error) {
 	entries := make([]types.SendMessageBatchRequestEntry, 0, len(messages))
-	for _, message := range messages {
+	for i, message := range messages {
 		payload, err := encoder(message)
 		if err != nil {
 			return nil, err
 		}
 
-		entry, err := mapToSendMessageEntry(string(payload), message.Attributes())
+		body := payload
+		if offload != nil {
+			if body, err = offload(message, payload); err != nil {
+				return nil, err
+			}
+		}
+
+		entry, err := mapToSendMessageEntry(body, message.Attributes())
 		if err != nil {
 			return nil, err
 		}
+		if err := mapFIFO(&entry, payload, message.Attributes()); err != nil {
+			return nil, err
+		}
+		entry.Id = awsbatch.EntryID(i)
 
 		entries = append(entries, entry)
 	}
@@ -43,19 +54,34 @@ func mapToSendMessageEntries(encoder broker.Encoder, messages ...broker.Message)
 	return entries, nil
 }
 
-func mapToSendMessageEntry(body string, attributes broker.Attributes) (types.SendMessageBatchRequestEntry, error) {
+func mapToSendMessageEntry(payload []byte, attributes broker.Attributes) (types.SendMessageBatchRequestEntry, error) {
+	body, encoding := awsbatch.EncodeBody(payload)
+
 	res := types.SendMessageBatchRequestEntry{
-		Id:                aws.String(uuid.NewString()),
 		MessageBody:       aws.String(body),
 		MessageAttributes: make(map[string]types.MessageAttributeValue),
 	}
 
 	for k, val := range attributes.Values() {
-		for _, v := range val {
-			res.MessageAttributes[k] = types.MessageAttributeValue{
-				DataType:    aws.String("String"),
-				StringValue: aws.String(v),
-			}
+		if k == MessageGroupID || k == MessageDeduplicationID || len(val) == 0 {
+			continue
+		}
+
+		value, err := awsbatch.AttributeValueOf(broker.AttributeTypeOf(attributes, k), val)
+		if err != nil {
+			return res, fmt.Errorf("invalid attribute %s: %w", k, err)
+		}
+		res.MessageAttributes[k] = types.MessageAttributeValue{
+			DataType:    aws.String(value.DataType),
+			StringValue: value.StringValue,
+			BinaryValue: value.BinaryValue,
+		}
+	}
+
+	if encoding != "" {
+		res.MessageAttributes[MessageContentEncoding] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(encoding),
 		}
 	}
 
@@ -70,3 +96,18 @@ func mapToSendMessageEntry(body string, attributes broker.Attributes) (types.Sen
 
 	return res, nil
 }
+
+// offloader replaces an oversized payload by a reference to where it was
+// stored.
+type offloader func(message broker.Message, payload []byte) ([]byte, error)
+
+// mapFIFO sets the group and deduplication IDs, see awsbatch.FIFO. FIFO
+// queues must have a group ID.
+func mapFIFO(entry *types.SendMessageBatchRequestEntry, payload []byte, attributes broker.Attributes) error {
+	destination := attributes.Get(MessageQueue)
+	entry.MessageGroupId, entry.MessageDeduplicationId = awsbatch.FIFO(destination, attributes, payload)
+	if awsbatch.IsFIFO(destination) && entry.MessageGroupId == nil {
+		return ErrNoGroupID
+	}
+	return nil
+}
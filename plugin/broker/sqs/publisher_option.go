@@ -2,21 +2,70 @@ package sqs
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
 type publisherOption struct {
-	encoder broker.Encoder
+	encoder     broker.Encoder
+	claimCheck  *broker.ClaimCheck
+	maxAttempts int
+	backoff     time.Duration
+	client      *sqs.Client
 }
 
 type PublisherOption func(*publisherOption)
 
+// WithEncoder sets how payloads are serialised, e.g. with
+// broker.Registry.Encoder to publish another content type. Binary output is
+// sent base64-encoded. Defaults to JSON.
+func WithEncoder(encoder broker.Encoder) PublisherOption {
+	return func(o *publisherOption) {
+		o.encoder = encoder
+	}
+}
+
+// WithClaimCheck offloads oversized payloads to the claim check's store and
+// sends their key instead. Subscribers restore them with WithRehydration.
+func WithClaimCheck(claimCheck *broker.ClaimCheck) PublisherOption {
+	return func(o *publisherOption) {
+		o.claimCheck = claimCheck
+	}
+}
+
+// WithRetry sends failed batch entries again, up to maxAttempts sends in
+// total, waiting backoff before the first retry and doubling it after each
+// up to awsbatch.MaxBackoff. A backoff of zero or less uses
+// awsbatch.DefaultBackoff. Requests failing as a whole are retried when the
+// error is transient (throttling, server or connection errors); entries
+// rejected as the sender's fault are not retried.
+func WithRetry(maxAttempts int, backoff time.Duration) PublisherOption {
+	return func(o *publisherOption) {
+		if backoff <= 0 {
+			backoff = awsbatch.DefaultBackoff
+		}
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// WithPublisherAwsClient publishes with client, e.g. one built by NewClient,
+// instead of a client from the default AWS configuration.
+func WithPublisherAwsClient(client *sqs.Client) PublisherOption {
+	return func(o *publisherOption) {
+		o.client = client
+	}
+}
+
 func newPublisherOption(opts ...PublisherOption) *publisherOption {
 	options := &publisherOption{
 		encoder: func(m broker.Message) ([]byte, error) {
 			return json.Marshal(m.Payload())
 		},
+		maxAttempts: 1,
 	}
 
 	for _, opt := range opts {
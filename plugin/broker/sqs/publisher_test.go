@@ -0,0 +1,211 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/awsbatch"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeSender fails whole requests with callErrs, in order, and then entries
+// listed in failures until their count runs out.
+type fakeSender struct {
+	calls    [][]string
+	sent     []types.SendMessageBatchRequestEntry
+	callErrs []error
+	failures map[string]int
+	fault    map[string]bool
+}
+
+func (f *fakeSender) SendMessageBatch(_ context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	if len(f.callErrs) > 0 {
+		err := f.callErrs[0]
+		f.callErrs = f.callErrs[1:]
+		f.calls = append(f.calls, nil)
+		return nil, err
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	var ids []string
+	f.sent = append(f.sent, in.Entries...)
+	for _, entry := range in.Entries {
+		id := aws.ToString(entry.Id)
+		ids = append(ids, id)
+
+		if f.failures[id] > 0 {
+			f.failures[id]--
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("InternalError"),
+				Message:     aws.String("try again"),
+				SenderFault: f.fault[id],
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, types.SendMessageBatchResultEntry{
+			Id:        entry.Id,
+			MessageId: aws.String("msg-" + id),
+		})
+	}
+	f.calls = append(f.calls, ids)
+	return out, nil
+}
+
+func newTestPublisher(sender *fakeSender, opts ...PublisherOption) *Publisher {
+	return &Publisher{options: newPublisherOption(opts...), provider: sender}
+}
+
+func TestPublisher_PublishBatchReportsEveryEntry(t *testing.T) {
+	sender := &fakeSender{failures: map[string]int{"3": 1, "11": 1}}
+	pub := newTestPublisher(sender)
+
+	messages := make([]broker.Message, 0, 12)
+	for i := range 12 {
+		messages = append(messages, NewMessage(order{ID: strconv.Itoa(i)}, "https://sqs/orders"))
+	}
+
+	result, err := pub.PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if len(sender.calls) != 2 {
+		t.Fatalf("expected two batches, got %d", len(sender.calls))
+	}
+
+	failed := result.Failed()
+	if len(failed) != 2 || failed[0].Message != messages[3] || failed[1].Message != messages[11] {
+		t.Fatalf("unexpected failed entries %+v", failed)
+	}
+	var sendErr *ErrSendMessage
+	if !errors.As(failed[1].Err, &sendErr) || sendErr.Error() != "status=InternalError message=try again" {
+		t.Fatalf("unexpected entry error %v", failed[1].Err)
+	}
+	if common.ClassifyFailureMode(failed[1].Err) != common.FailureModeRecoverable {
+		t.Fatal("expected server-side failures to be recoverable")
+	}
+	if result.Entries[0].MessageID != "msg-0" || result.Entries[0].Err != nil {
+		t.Fatalf("unexpected successful entry %+v", result.Entries[0])
+	}
+	if err := pub.Publish(context.Background(), messages...); err != nil {
+		t.Fatalf("expected failures to be consumed, got %v", err)
+	}
+}
+
+func TestPublisher_RetriesOnlyFailedEntries(t *testing.T) {
+	sender := &fakeSender{
+		failures: map[string]int{"1": 2, "2": 5},
+		fault:    map[string]bool{"2": true},
+	}
+	pub := newTestPublisher(sender, WithRetry(3, time.Millisecond))
+
+	messages := []broker.Message{
+		NewMessage(order{ID: "o-0"}, "https://sqs/orders"),
+		NewMessage(order{ID: "o-1"}, "https://sqs/orders"),
+		NewMessage(order{ID: "o-2"}, "https://sqs/orders"),
+	}
+	result, err := pub.PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	if len(sender.calls) != 3 || len(sender.calls[1]) != 1 || sender.calls[1][0] != "1" {
+		t.Fatalf("expected only entry 1 to be retried, got calls %v", sender.calls)
+	}
+	if result.Entries[1].Err != nil || result.Entries[1].MessageID != "msg-1" {
+		t.Fatalf("expected retried entry to succeed, got %+v", result.Entries[1])
+	}
+	if failed := result.Failed(); len(failed) != 1 || failed[0].Message != messages[2] {
+		t.Fatalf("expected the sender fault to remain failed, got %+v", failed)
+	}
+}
+
+func TestPublisher_RetriesTransientRequestFailures(t *testing.T) {
+	messages := []broker.Message{
+		NewMessage(order{ID: "o-0"}, "https://sqs/orders"),
+		NewMessage(order{ID: "o-1"}, "https://sqs/orders"),
+	}
+
+	sender := &fakeSender{callErrs: []error{&smithy.GenericAPIError{Code: "ThrottlingException"}}}
+	result, err := newTestPublisher(sender, WithRetry(2, time.Millisecond)).PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if len(sender.calls) != 2 || len(sender.calls[1]) != 2 {
+		t.Fatalf("expected the throttled request to be sent again, got calls %v", sender.calls)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("expected every entry to succeed on retry, got %v", err)
+	}
+
+	invalid := &smithy.GenericAPIError{Code: "InvalidParameterValue", Fault: smithy.FaultClient}
+	sender = &fakeSender{callErrs: []error{invalid}}
+	result, err = newTestPublisher(sender, WithRetry(3, time.Millisecond)).PublishBatch(context.Background(), messages...)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if len(sender.calls) != 1 {
+		t.Fatalf("expected a client error not to be retried, got calls %v", sender.calls)
+	}
+	if failed := result.Failed(); len(failed) != 2 || !errors.Is(failed[0].Err, invalid) {
+		t.Fatalf("expected both entries to carry the request error, got %+v", failed)
+	}
+}
+
+func TestPublisher_ClaimCheckMeasuresEncodedBody(t *testing.T) {
+	binary := func(broker.Message) ([]byte, error) { return bytes.Repeat([]byte{0xff}, 900), nil }
+	store := memoryStore{}
+	fake := &fakeSender{}
+	pub := &Publisher{
+		options:  newPublisherOption(WithEncoder(binary), WithClaimCheck(broker.NewClaimCheck(store, 1024))),
+		provider: fake,
+	}
+
+	msg := NewMessage(order{ID: "o-1"}, "https://sqs/orders")
+	if err := pub.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if _, ok := msg.Attributes().Lookup(broker.ClaimCheckAttribute); !ok || len(store) != 1 {
+		t.Fatal("expected a binary payload whose base64 body exceeds the threshold to be offloaded")
+	}
+}
+
+func TestPublisher_ClaimCheckDeduplicatesOnPayload(t *testing.T) {
+	large := func(broker.Message) ([]byte, error) { return bytes.Repeat([]byte("a"), 2048), nil }
+	store := memoryStore{}
+	fake := &fakeSender{}
+	pub := newTestPublisher(fake, WithEncoder(large), WithClaimCheck(broker.NewClaimCheck(store, 1024)))
+
+	queue := "https://sqs/orders.fifo"
+	for range 2 {
+		if err := pub.Publish(context.Background(), NewMessage(order{ID: "o-1"}, queue, WithGroupID("customer-1"))); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	if len(fake.sent) != 2 || len(store) != 2 {
+		t.Fatalf("expected both payloads offloaded and sent, got %d sent and %d stored", len(fake.sent), len(store))
+	}
+	first, second := fake.sent[0], fake.sent[1]
+	if aws.ToString(first.MessageBody) == aws.ToString(second.MessageBody) {
+		t.Fatal("expected distinct claim-check keys")
+	}
+	if aws.ToString(first.MessageDeduplicationId) != aws.ToString(second.MessageDeduplicationId) {
+		t.Fatal("expected the same payload to share a deduplication id")
+	}
+}
+
+func TestWithRetry_ReplacesZeroBackoff(t *testing.T) {
+	if got := newPublisherOption(WithRetry(3, 0)).backoff; got != awsbatch.DefaultBackoff {
+		t.Fatalf("expected the default backoff, got %s", got)
+	}
+}
@@ -3,12 +3,27 @@ package sqs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
-var ErrNoMessageInQueue = errors.New("no message in queue")
+var (
+	// ErrNoMessageInQueue wraps broker.ErrNoMessages.
+	ErrNoMessageInQueue = fmt.Errorf("no message in queue: %w", broker.ErrNoMessages)
+	ErrCommitBatch      = errors.New("failed to delete messages")
+)
+
+const (
+	// maxBatchSize is the most messages SQS sends, receives or deletes per request.
+	maxBatchSize = 10
+	// maxVisibilityTimeout is the SQS limit of 12 hours, in seconds.
+	maxVisibilityTimeout = 12 * 60 * 60
+)
 
 type Subscriber struct {
 	options  *subscriberOptions
@@ -16,10 +31,13 @@ type Subscriber struct {
 	queue    string
 }
 
-var _ broker.Subscriber = (*Subscriber)(nil)
+var (
+	_ broker.BatchSubscriber = (*Subscriber)(nil)
+	_ broker.Extender        = (*Subscriber)(nil)
+)
 
 func (s *Subscriber) Subscribe(ctx context.Context) (broker.Message, error) {
-	result, err := s.provider.ReceiveMessage(ctx, mapToReceiveMessageInput(s.queue))
+	result, err := s.provider.ReceiveMessage(ctx, mapToReceiveMessageInput(s.queue, 1))
 	if err != nil {
 		return nil, err
 	}
@@ -28,8 +46,7 @@ func (s *Subscriber) Subscribe(ctx context.Context) (broker.Message, error) {
 		return nil, ErrNoMessageInQueue
 	}
 
-	return mapProviderToMessage(
-		s.options.decoder, s.queue, result.Messages[0])
+	return mapProviderToMessage(ctx, s.options, s.queue, result.Messages[0])
 }
 
 func (s *Subscriber) Commit(ctx context.Context, message broker.Message) error {
@@ -48,13 +65,65 @@ func (s *Subscriber) Commit(ctx context.Context, message broker.Message) error {
 	return nil
 }
 
+// SubscribeBatch receives up to limit messages, at most 10, in one request.
+// Messages that fail to decode are left in the queue and reported in the
+// returned error alongside the decoded ones.
+func (s *Subscriber) SubscribeBatch(ctx context.Context, limit int) ([]broker.Message, error) {
+	result, err := s.provider.ReceiveMessage(ctx, mapToReceiveMessageInput(s.queue, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Messages) == 0 {
+		return nil, ErrNoMessageInQueue
+	}
+
+	messages := make([]broker.Message, 0, len(result.Messages))
+	var errs []error
+	for _, m := range result.Messages {
+		message, err := mapProviderToMessage(ctx, s.options, s.queue, m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, errors.Join(errs...)
+}
+
+// CommitBatch deletes messages with DeleteMessageBatch, 10 per request.
+func (s *Subscriber) CommitBatch(ctx context.Context, messages []broker.Message) error {
+	var errs []error
+	for chunk := range slices.Chunk(messages, maxBatchSize) {
+		result, err := s.provider.DeleteMessageBatch(ctx, mapToCommitMessageBatchInput(s.queue, chunk))
+		if err != nil {
+			return err
+		}
+
+		for _, failed := range result.Failed {
+			errs = append(errs, fmt.Errorf("%w: id=%s code=%s message=%s", ErrCommitBatch,
+				aws.ToString(failed.Id), aws.ToString(failed.Code), aws.ToString(failed.Message)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Extend sets the message visibility timeout to d from now with
+// ChangeMessageVisibility, rounded up to whole seconds.
+func (s *Subscriber) Extend(ctx context.Context, message broker.Message, d time.Duration) error {
+	_, err := s.provider.ChangeMessageVisibility(ctx, mapToChangeVisibilityInput(s.queue, message, d))
+	return err
+}
+
 func (s *Subscriber) Close(ctx context.Context) {}
 
 func NewSubscriber(ctx context.Context, queue string, opts ...SubscriberOption) (*Subscriber, error) {
 	options := newSubscriberOption(opts...)
 
 	if options.client == nil {
-		provider, err := newClient(ctx)
+		provider, err := NewClient(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -1,6 +1,12 @@
 package sqs
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
@@ -9,14 +15,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
-func mapToReceiveMessageInput(queue string) *sqs.ReceiveMessageInput {
+func mapToReceiveMessageInput(queue string, limit int) *sqs.ReceiveMessageInput {
 	return &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(queue),
-		MaxNumberOfMessages: 1,
+		MaxNumberOfMessages: int32(min(max(limit, 1), maxBatchSize)),
 		WaitTimeSeconds:     20,
 		MessageAttributeNames: []string{
 			string(types.QueueAttributeNameAll),
 		},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameMessageDeduplicationId,
+			types.MessageSystemAttributeNameMessageGroupId,
+		},
 	}
 }
 
@@ -29,22 +40,117 @@ func mapToCommitMessageInput(queue string, message broker.Message) *sqs.DeleteMe
 	}
 }
 
-func mapProviderToMessage(decoder broker.Decoder, queue string, m types.Message) (broker.Message, error) {
-	body, err := decoder([]byte(*m.Body))
-	if err != nil {
-		return nil, err
+func mapToCommitMessageBatchInput(queue string, messages []broker.Message) *sqs.DeleteMessageBatchInput {
+	entries := make([]types.DeleteMessageBatchRequestEntry, 0, len(messages))
+	for i, message := range messages {
+		entries = append(entries, types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: aws.String(message.Attributes().Get(MessageReceiptHandle)),
+		})
+	}
+
+	return &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(queue),
+		Entries:  entries,
 	}
+}
+
+func mapToChangeVisibilityInput(queue string, message broker.Message, d time.Duration) *sqs.ChangeMessageVisibilityInput {
+	seconds := (d + time.Second - 1) / time.Second
 
+	return &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queue),
+		ReceiptHandle:     aws.String(message.Attributes().Get(MessageReceiptHandle)),
+		VisibilityTimeout: int32(min(seconds, maxVisibilityTimeout)),
+	}
+}
+
+func mapProviderToMessage(ctx context.Context, options *subscriberOptions, queue string, m types.Message) (broker.Message, error) {
 	res := &message{
-		data:      body,
 		createdAt: time.Now(),
 		attr:      newAttributes(),
 	}
 
+	for k, v := range m.MessageAttributes {
+		switch k {
+		case MessageID, MessageIdempotencyKey, MessageReceiptHandle, MessageQueue, MessageContentEncoding, MessageReceiveCount,
+			MessageGroupID, MessageDeduplicationID:
+			continue
+		}
+		if err := mapMessageAttributeValue(res.attr, k, v); err != nil {
+			return nil, fmt.Errorf("invalid attribute %s: %w", k, err)
+		}
+	}
+
+	body := []byte(aws.ToString(m.Body))
+	if encoding, ok := m.MessageAttributes[MessageContentEncoding]; ok &&
+		aws.ToString(encoding.StringValue) == contentEncodingBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+
+	var err error
+	if options.claimCheck != nil {
+		if body, err = options.claimCheck.Rehydrate(ctx, res.Attributes(), body); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.contentDecoder != nil {
+		res.data, err = options.contentDecoder(res.Attributes(), body)
+	} else {
+		res.data, err = options.decoder(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	res.Attributes().Add(MessageID, *m.MessageId)
 	res.Attributes().Add(MessageIdempotencyKey, m.Attributes["MessageDeduplicationId"])
 	res.Attributes().Add(MessageReceiptHandle, *m.ReceiptHandle)
 	res.Attributes().Add(MessageQueue, queue)
+	if group, ok := m.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; ok {
+		res.Attributes().Add(MessageGroupID, group)
+	}
+	if count, ok := m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]; ok {
+		res.Attributes().Add(MessageReceiveCount, count)
+	}
 
 	return res, nil
 }
+
+// mapMessageAttributeValue adds v to attr under key, recording its type.
+// Custom type labels such as Number.int are reduced to their base type, and
+// non-string String.Array elements keep their JSON text.
+func mapMessageAttributeValue(attr *attributes, key string, v types.MessageAttributeValue) error {
+	dataType := aws.ToString(v.DataType)
+	switch {
+	case dataType == string(broker.AttributeTypeStringArray):
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(aws.ToString(v.StringValue)), &raw); err != nil {
+			return err
+		}
+
+		values := make([]string, 0, len(raw))
+		for _, r := range raw {
+			var value string
+			if json.Unmarshal(r, &value) != nil {
+				value = string(r)
+			}
+			values = append(values, value)
+		}
+		broker.AddStringArray(attr, key, values...)
+	case strings.HasPrefix(dataType, string(broker.AttributeTypeBinary)):
+		broker.AddBinary(attr, key, v.BinaryValue)
+	case strings.HasPrefix(dataType, string(broker.AttributeTypeNumber)):
+		attr.Add(key, aws.ToString(v.StringValue))
+		attr.SetType(key, broker.AttributeTypeNumber)
+	case v.StringValue != nil:
+		attr.Add(key, *v.StringValue)
+	}
+
+	return nil
+}
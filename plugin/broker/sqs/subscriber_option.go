@@ -10,8 +10,10 @@ import (
 )
 
 type subscriberOptions struct {
-	decoder broker.Decoder
-	client  *sqs.Client
+	decoder        broker.Decoder
+	contentDecoder broker.ContentDecoder
+	claimCheck     *broker.ClaimCheck
+	client         *sqs.Client
 }
 
 type SubscriberOption func(*subscriberOptions)
@@ -22,6 +24,15 @@ func WithDecoder(decoder broker.Decoder) SubscriberOption {
 	}
 }
 
+// WithContentDecoder decodes each body according to its
+// broker.ContentTypeAttribute message attribute, e.g. with
+// broker.Registry.DecoderTarget. It takes precedence over WithDecoder.
+func WithContentDecoder(decoder broker.ContentDecoder) SubscriberOption {
+	return func(so *subscriberOptions) {
+		so.contentDecoder = decoder
+	}
+}
+
 func WithDecoderTarget(typeof any) SubscriberOption {
 	return func(s *subscriberOptions) {
 		v := reflect.TypeOf(typeof)
@@ -73,6 +84,14 @@ func WithSNSEventDecoder(typeof any) SubscriberOption {
 	}
 }
 
+// WithRehydration loads payloads offloaded by a publisher's WithClaimCheck
+// before decoding them. Messages from SNS need raw message delivery.
+func WithRehydration(claimCheck *broker.ClaimCheck) SubscriberOption {
+	return func(s *subscriberOptions) {
+		s.claimCheck = claimCheck
+	}
+}
+
 func WithAwsClient(client *sqs.Client) SubscriberOption {
 	return func(s *subscriberOptions) {
 		s.client = client
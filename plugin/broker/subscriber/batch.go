@@ -0,0 +1,142 @@
+package subscriber
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// BatchHook defines a function type for processing a batch of messages
+type BatchHook[T any] func(ctx context.Context, vals []T) error
+
+// WithBatchSize sets how many messages a BatchHandler requests at once.
+// Defaults to 10.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// BatchHandler receives messages from a broker.BatchSubscriber and hands each
+// batch to a BatchHook. A nil error commits the batch with CommitBatch;
+// otherwise every message of the batch is settled as in Handler. Middlewares
+// and partitioning do not apply.
+type BatchHandler[T any] struct {
+	settler
+	batch   broker.BatchSubscriber
+	handler BatchHook[T]
+	wg      sync.WaitGroup
+	stop    chan struct{}
+}
+
+// NewBatchHandler creates a new BatchHandler instance
+func NewBatchHandler[T any](subscribe broker.BatchSubscriber, fn BatchHook[T], opts ...Option) *BatchHandler[T] {
+	o := &options{batchSize: 10}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &BatchHandler[T]{
+		settler: settler{subscribe: subscribe, options: o},
+		batch:   subscribe,
+		handler: fn,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start begins batch processing with configurable workers
+func (h *BatchHandler[T]) Start(ctx context.Context, workerCount int) error {
+	if workerCount < 1 {
+		workerCount = 1
+	}
+
+	h.wg.Add(workerCount)
+	for range workerCount {
+		go h.worker(ctx)
+	}
+
+	return nil
+}
+
+// Stop gracefully shuts down the handler
+func (h *BatchHandler[T]) Stop() {
+	close(h.stop)
+	h.wg.Wait()
+}
+
+func (h *BatchHandler[T]) worker(ctx context.Context) {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ctx.Done():
+			return
+		default:
+			msgs, ok := poll(ctx, h.stop, h.options, h.receive)
+			if !ok {
+				return
+			}
+
+			h.process(ctx, msgs)
+		}
+	}
+}
+
+// receive fetches the next batch. Decode errors returned alongside decoded
+// messages are reported without holding the batch back.
+func (h *BatchHandler[T]) receive(ctx context.Context) ([]broker.Message, error) {
+	msgs, err := h.batch.SubscribeBatch(ctx, h.options.batchSize)
+	if err != nil && len(msgs) > 0 {
+		if h.options.hooks.OnSubscribeError != nil {
+			h.options.hooks.OnSubscribeError(ctx, err)
+		}
+		return msgs, nil
+	}
+
+	return msgs, err
+}
+
+// process unwraps the decoded payloads, runs the hook and settles the batch.
+func (h *BatchHandler[T]) process(ctx context.Context, msgs []broker.Message) {
+	vals := make([]T, 0, len(msgs))
+	accepted := make([]broker.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		val, ok := msg.Payload().(*T)
+		if !ok {
+			h.settle(ctx, msg, ErrUnexpectedSchema)
+			continue
+		}
+		vals = append(vals, *val)
+		accepted = append(accepted, msg)
+	}
+
+	if len(accepted) == 0 {
+		return
+	}
+
+	stop := h.heartbeat(ctx, accepted...)
+	err := h.handler(ctx, vals)
+	stop()
+
+	if err != nil {
+		for _, msg := range accepted {
+			h.settle(ctx, msg, err)
+		}
+		return
+	}
+
+	if err := h.batch.CommitBatch(ctx, accepted); err != nil {
+		logger.Of(ctx).ErrorS("Subscriber::BatchHandler::Commit", logger.WithValue("error", err))
+		return
+	}
+
+	if h.options.hooks.OnSuccess != nil {
+		for _, msg := range accepted {
+			h.options.hooks.OnSuccess(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package subscriber
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+)
+
+func TestBatchHandler_CommitsBatches(t *testing.T) {
+	b := inmem.New()
+	batches := make(chan []order, 4)
+
+	for _, id := range []string{"o-1", "o-2", "o-3", "o-4", "o-5"} {
+		publish(t, b, id)
+	}
+
+	h := NewBatchHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, orders []order) error {
+			batches <- orders
+			return nil
+		},
+		WithBatchSize(3),
+	)
+	start(t, h, 1)
+
+	var got []string
+	for len(got) < 5 {
+		select {
+		case batch := <-batches:
+			if len(batch) > 3 {
+				t.Fatalf("expected batches of at most 3, got %d", len(batch))
+			}
+			for _, o := range batch {
+				got = append(got, o.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out, received %v", got)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for b.Len("orders") != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := b.Len("orders"); n != 0 {
+		t.Fatalf("expected every batch to be committed, %d left", n)
+	}
+	if got[0] != "o-1" || got[4] != "o-5" {
+		t.Fatalf("unexpected order %v", got)
+	}
+}
+
+func TestBatchHandler_SettlesFailedBatch(t *testing.T) {
+	b := inmem.New()
+	dropped := make(chan string, 2)
+
+	publish(t, b, "o-1")
+	publish(t, b, "o-2")
+
+	h := NewBatchHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, orders []order) error {
+			return classifiedError{mode: common.FailureModeDrop}
+		},
+		WithHooks(Hooks{OnDrop: func(_ context.Context, msg broker.Message, _ error) {
+			dropped <- msg.Payload().(*order).ID
+		}}),
+	)
+	start(t, h, 1)
+
+	await(t, dropped)
+	await(t, dropped)
+	if n := b.Len("orders"); n != 0 {
+		t.Fatalf("expected dropped batch to be committed, %d left", n)
+	}
+}
+
+// flakyBatchSubscriber fails SubscribeBatch with errs, in order, before
+// delegating to the wrapped subscriber.
+type flakyBatchSubscriber struct {
+	broker.BatchSubscriber
+	mu   sync.Mutex
+	errs []error
+}
+
+func (f *flakyBatchSubscriber) SubscribeBatch(ctx context.Context, limit int) ([]broker.Message, error) {
+	f.mu.Lock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		f.mu.Unlock()
+		return nil, err
+	}
+	f.mu.Unlock()
+
+	return f.BatchSubscriber.SubscribeBatch(ctx, limit)
+}
+
+func TestBatchHandler_ReportsOnlySubscribeFailures(t *testing.T) {
+	b := inmem.New()
+	publish(t, b, "o-1")
+
+	sub := &flakyBatchSubscriber{
+		BatchSubscriber: b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		errs:            []error{broker.ErrNoMessages, broker.ErrNoMessages, errors.New("throttled")},
+	}
+	reported := make(chan string, 3)
+	done := make(chan string, 1)
+
+	h := NewBatchHandler(sub,
+		func(_ context.Context, orders []order) error {
+			done <- orders[0].ID
+			return nil
+		},
+		WithSubscribeBackoff(func(int) time.Duration { return time.Millisecond }),
+		WithHooks(Hooks{OnSubscribeError: func(_ context.Context, err error) {
+			reported <- err.Error()
+		}}),
+	)
+	start(t, h, 1)
+
+	if got := await(t, done); got != "o-1" {
+		t.Fatalf("unexpected batch %q", got)
+	}
+	if got := await(t, reported); got != "throttled" {
+		t.Fatalf("unexpected reported error %q", got)
+	}
+	if len(reported) != 0 {
+		t.Fatal("expected empty receives not to be reported")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/aawadallak/go-core-kit/core/broker"
 )
@@ -18,21 +19,58 @@ var (
 // Hook defines a function type for processing messages
 type Hook[T any] func(ctx context.Context, val T) error
 
-// Handler manages subscription and message processing
+// Option configures a Handler
+type Option func(*options)
+
+type options struct {
+	middlewares        []broker.SubscriberMiddleware
+	retry              RetryPolicy
+	deadLetter         broker.Publisher
+	deadLetterFactory  DeadLetterFactory
+	hooks              Hooks
+	partitionKey       PartitionKeyFunc
+	maxInFlight        int
+	batchSize          int
+	heartbeatInterval  time.Duration
+	heartbeatExtension time.Duration
+	subscribeBackoff   func(failures int) time.Duration
+}
+
+// WithMiddleware wraps message handling with mws, the first being the outermost.
+// A message is committed only when the whole chain returns nil.
+func WithMiddleware(mws ...broker.SubscriberMiddleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, mws...)
+	}
+}
+
+// Handler manages subscription and message processing. Failed messages are
+// settled by common.ClassifyFailureMode: recoverable errors are retried,
+// drop errors are committed and discarded, and everything else is
+// dead-lettered.
 type Handler[T any] struct {
-	subscribe broker.Subscriber
-	handler   Hook[T]
-	wg        sync.WaitGroup
-	stop      chan struct{}
+	settler
+	handler Hook[T]
+	handle  broker.MessageHandler
+	wg      sync.WaitGroup
+	stop    chan struct{}
 }
 
 // NewHandler creates a new Handler instance
-func NewHandler[T any](subscribe broker.Subscriber, fn Hook[T]) *Handler[T] {
-	return &Handler[T]{
-		subscribe: subscribe,
-		handler:   fn,
-		stop:      make(chan struct{}),
+func NewHandler[T any](subscribe broker.Subscriber, fn Hook[T], opts ...Option) *Handler[T] {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
 	}
+
+	h := &Handler[T]{
+		settler: settler{subscribe: subscribe, options: o},
+		handler: fn,
+		stop:    make(chan struct{}),
+	}
+	h.handle = broker.ChainSubscriber(h.process, o.middlewares...)
+
+	return h
 }
 
 // Start begins message processing with configurable workers
@@ -41,6 +79,11 @@ func (h *Handler[T]) Start(ctx context.Context, workerCount int) error {
 		workerCount = 1
 	}
 
+	if h.options.partitionKey != nil {
+		h.startPartitioned(ctx, workerCount)
+		return nil
+	}
+
 	h.wg.Add(workerCount)
 	for range workerCount {
 		go h.worker(ctx)
@@ -55,6 +98,16 @@ func (h *Handler[T]) Stop() {
 	h.wg.Wait()
 }
 
+// process unwraps the decoded payload and runs the hook.
+func (h *Handler[T]) process(ctx context.Context, msg broker.Message) error {
+	val, ok := msg.Payload().(*T)
+	if !ok {
+		return ErrUnexpectedSchema
+	}
+
+	return h.handler(ctx, *val)
+}
+
 func (h *Handler[T]) worker(ctx context.Context) {
 	defer h.wg.Done()
 
@@ -65,26 +118,21 @@ func (h *Handler[T]) worker(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			msg, err := h.subscribe.Subscribe(ctx)
-			if err != nil {
-				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-					return
-				}
-				continue
-			}
-
-			val, ok := msg.Payload().(*T)
+			msg, ok := h.receive(ctx)
 			if !ok {
-				continue
+				return
 			}
 
-			if err := h.handler(ctx, *val); err != nil {
-				continue
-			}
+			stop := h.heartbeat(ctx, msg)
+			err := h.handle(ctx, msg)
+			stop()
 
-			if err := h.subscribe.Commit(ctx, msg); err != nil {
-				continue
-			}
+			h.settle(ctx, msg, err)
 		}
 	}
 }
+
+// receive returns the next message, or false once the handler is stopping.
+func (h *Handler[T]) receive(ctx context.Context) (broker.Message, bool) {
+	return poll(ctx, h.stop, h.options, h.subscribe.Subscribe)
+}
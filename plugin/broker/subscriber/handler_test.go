@@ -0,0 +1,274 @@
+package subscriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+)
+
+type order struct {
+	ID string `json:"id"`
+}
+
+type classifiedError struct {
+	mode common.FailureMode
+}
+
+func (e classifiedError) Error() string                   { return string(e.mode) }
+func (e classifiedError) FailureMode() common.FailureMode { return e.mode }
+
+type runner interface {
+	Start(ctx context.Context, workerCount int) error
+	Stop()
+}
+
+func start(t *testing.T, h runner, workers int) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := h.Start(ctx, workers); err != nil {
+		t.Fatalf("unexpected start error: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		h.Stop()
+	})
+}
+
+func await(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for hook")
+		return ""
+	}
+}
+
+func publish(t *testing.T, b *inmem.Broker, id string) {
+	t.Helper()
+	if err := b.Publish(context.Background(), inmem.NewMessage(order{ID: id}, "orders")); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+}
+
+func TestHandler_DropCommits(t *testing.T) {
+	b := inmem.New()
+	dropped := make(chan string, 1)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			return classifiedError{mode: common.FailureModeDrop}
+		},
+		WithHooks(Hooks{OnDrop: func(_ context.Context, msg broker.Message, _ error) {
+			dropped <- msg.Payload().(*order).ID
+		}}),
+	)
+	start(t, h, 1)
+	publish(t, b, "o-1")
+
+	if got := await(t, dropped); got != "o-1" {
+		t.Fatalf("unexpected dropped message %q", got)
+	}
+	if n := b.Len("orders"); n != 0 {
+		t.Fatalf("expected dropped message to be committed, %d left", n)
+	}
+}
+
+func TestHandler_RetryThenDeadLetter(t *testing.T) {
+	b := inmem.New(inmem.WithVisibilityTimeout(time.Minute))
+	retried := make(chan string, 1)
+	deadLettered := make(chan string, 1)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			return classifiedError{mode: common.FailureModeRecoverable}
+		},
+		WithRetry(RetryPolicy{MaxAttempts: 2}),
+		WithDeadLetter(b, func(dl DeadLetter) broker.Message {
+			return inmem.NewMessage(dl, "orders-dlq")
+		}),
+		WithHooks(Hooks{
+			OnRetry: func(_ context.Context, msg broker.Message, _ error, attempt int) {
+				retried <- msg.Payload().(*order).ID
+			},
+			OnDeadLetter: func(_ context.Context, msg broker.Message, _ error) {
+				deadLettered <- msg.Payload().(*order).ID
+			},
+		}),
+	)
+	start(t, h, 1)
+	publish(t, b, "o-1")
+
+	await(t, retried)
+	if n := b.Len("orders"); n != 1 {
+		t.Fatalf("expected retried message to stay uncommitted, %d left", n)
+	}
+
+	b.Advance(time.Minute)
+	if got := await(t, deadLettered); got != "o-1" {
+		t.Fatalf("unexpected dead-lettered message %q", got)
+	}
+	if n := b.Len("orders"); n != 0 {
+		t.Fatalf("expected dead-lettered message to be committed, %d left", n)
+	}
+
+	dlq := b.NewSubscriber("orders-dlq", inmem.WithDecoderTarget(map[string]any{}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := dlq.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected dlq subscribe error: %v", err)
+	}
+	envelope := *msg.Payload().(*map[string]any)
+	if envelope["failure_mode"] != string(common.FailureModeRecoverable) || envelope["attempts"] != float64(2) {
+		t.Fatalf("unexpected envelope %+v", envelope)
+	}
+	if payload, _ := json.Marshal(envelope["payload"]); string(payload) != `{"id":"o-1"}` {
+		t.Fatalf("unexpected envelope payload %s", payload)
+	}
+}
+
+func TestHandler_RetriesUnclassifiedFailures(t *testing.T) {
+	b := inmem.New(inmem.WithVisibilityTimeout(time.Minute))
+	retried := make(chan string, 1)
+	deadLettered := make(chan string, 1)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			return errors.New("connection reset")
+		},
+		WithRetry(RetryPolicy{MaxAttempts: 2}),
+		WithDeadLetter(b, func(dl DeadLetter) broker.Message {
+			return inmem.NewMessage(dl, "orders-dlq")
+		}),
+		WithHooks(Hooks{
+			OnRetry: func(_ context.Context, msg broker.Message, _ error, _ int) {
+				retried <- msg.Payload().(*order).ID
+			},
+			OnDeadLetter: func(_ context.Context, msg broker.Message, _ error) {
+				deadLettered <- msg.Payload().(*order).ID
+			},
+		}),
+	)
+	start(t, h, 1)
+	publish(t, b, "o-1")
+
+	await(t, retried)
+	if n := b.Len("orders-dlq"); n != 0 {
+		t.Fatalf("expected no dead letter before retries run out, got %d", n)
+	}
+
+	b.Advance(time.Minute)
+	await(t, deadLettered)
+
+	dlq := b.NewSubscriber("orders-dlq", inmem.WithDecoderTarget(map[string]any{}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := dlq.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("unexpected dlq subscribe error: %v", err)
+	}
+	envelope := *msg.Payload().(*map[string]any)
+	if envelope["failure_mode"] != string(common.FailureModeUnknown) || envelope["attempts"] != float64(2) {
+		t.Fatalf("unexpected envelope %+v", envelope)
+	}
+}
+
+func TestHandler_RepublishWithDelay(t *testing.T) {
+	b := inmem.New()
+	attempts := make(chan string, 2)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			return classifiedError{mode: common.FailureModeRecoverable}
+		},
+		WithRetry(RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     ExponentialBackoff(time.Second, time.Minute),
+			Publisher:   b,
+			Factory: func(msg broker.Message) broker.Message {
+				return inmem.NewMessage(msg.Payload(), "orders")
+			},
+		}),
+		WithHooks(Hooks{OnRetry: func(_ context.Context, msg broker.Message, _ error, attempt int) {
+			attempts <- msg.Attributes().Get(RetryAttemptAttribute)
+		}}),
+	)
+	start(t, h, 1)
+	publish(t, b, "o-1")
+
+	if got := await(t, attempts); got != "" {
+		t.Fatalf("expected first delivery without retry attribute, got %q", got)
+	}
+	if n := b.Len("orders"); n != 1 {
+		t.Fatalf("expected original committed and one re-published, %d left", n)
+	}
+
+	b.Advance(time.Second)
+	if got := await(t, attempts); got != "1" {
+		t.Fatalf("expected re-published retry attempt 1, got %q", got)
+	}
+}
+
+func TestHandler_UnexpectedSchemaIsDeadLettered(t *testing.T) {
+	b := inmem.New()
+	deadLettered := make(chan string, 1)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(map[string]any{})),
+		func(_ context.Context, o order) error { return nil },
+		WithDeadLetter(b, func(dl DeadLetter) broker.Message {
+			return inmem.NewMessage(dl, "orders-dlq")
+		}),
+		WithHooks(Hooks{OnDeadLetter: func(_ context.Context, _ broker.Message, err error) {
+			deadLettered <- err.Error()
+		}}),
+	)
+	start(t, h, 1)
+	publish(t, b, "o-1")
+
+	if got := await(t, deadLettered); got != ErrUnexpectedSchema.Error() {
+		t.Fatalf("unexpected error %q", got)
+	}
+	if n := b.Len("orders-dlq"); n != 1 {
+		t.Fatalf("expected one dead letter, got %d", n)
+	}
+}
+
+func TestSettler_CapsRetryDelay(t *testing.T) {
+	var published broker.Message
+	s := &settler{options: &options{retry: RetryPolicy{
+		Backoff: func(int) time.Duration { return time.Hour },
+		Publisher: broker.PublisherFunc(func(_ context.Context, msgs ...broker.Message) error {
+			published = msgs[0]
+			return nil
+		}),
+		Factory: func(msg broker.Message) broker.Message {
+			return inmem.NewMessage(msg.Payload(), "orders")
+		},
+	}}, subscribe: inmem.New().NewSubscriber("orders")}
+
+	s.retry(context.Background(), inmem.NewMessage(order{ID: "o-1"}, "orders"), errors.New("busy"), 1)
+
+	if published == nil {
+		t.Fatal("expected the message to be re-published")
+	}
+	if got := published.Attributes().Get(DelaySecondAttribute); got != "900" {
+		t.Fatalf("expected the delay capped at 900 seconds, got %q", got)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := backoff(retry); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", retry, got, want)
+		}
+	}
+}
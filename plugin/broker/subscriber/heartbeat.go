@@ -0,0 +1,62 @@
+package subscriber
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// WithHeartbeat keeps messages hidden from other consumers while they are
+// handled when the subscriber implements broker.Extender: every interval,
+// each message is extended by extension, which defaults to twice the
+// interval. The interval should be well below the queue's visibility timeout.
+func WithHeartbeat(interval, extension time.Duration) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+		o.heartbeatExtension = extension
+		if extension <= 0 {
+			o.heartbeatExtension = 2 * interval
+		}
+	}
+}
+
+// heartbeat extends msgs until the returned function is called.
+func (s *settler) heartbeat(ctx context.Context, msgs ...broker.Message) func() {
+	extender, ok := s.subscribe.(broker.Extender)
+	if !ok || s.options.heartbeatInterval <= 0 || len(msgs) == 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(s.options.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, msg := range msgs {
+					if err := extender.Extend(ctx, msg, s.options.heartbeatExtension); err != nil {
+						logger.Of(ctx).WarnS("Subscriber::Handler::Heartbeat", logger.WithValue("error", err))
+					}
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,39 @@
+package subscriber
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+)
+
+func TestHandler_HeartbeatPreventsRedelivery(t *testing.T) {
+	b := inmem.New(inmem.WithVisibilityTimeout(100 * time.Millisecond))
+	var deliveries atomic.Int32
+	done := make(chan string, 2)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			deliveries.Add(1)
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		},
+		WithHeartbeat(20*time.Millisecond, 100*time.Millisecond),
+		WithHooks(Hooks{OnSuccess: func(_ context.Context, msg broker.Message) {
+			done <- msg.Payload().(*order).ID
+		}}),
+	)
+	start(t, h, 2)
+	publish(t, b, "o-1")
+
+	await(t, done)
+	if n := deliveries.Load(); n != 1 {
+		t.Fatalf("expected a single delivery while the handler ran, got %d", n)
+	}
+	if n := b.Len("orders"); n != 0 {
+		t.Fatalf("expected message to be committed, %d left", n)
+	}
+}
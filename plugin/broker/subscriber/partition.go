@@ -0,0 +1,114 @@
+package subscriber
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// PartitionKeyFunc extracts the ordering key of a message, e.g. an entity ID.
+type PartitionKeyFunc func(msg broker.Message) string
+
+// PartitionByAttribute returns a PartitionKeyFunc reading the attribute key.
+func PartitionByAttribute(key string) PartitionKeyFunc {
+	return func(msg broker.Message) string {
+		return msg.Attributes().Get(key)
+	}
+}
+
+// WithPartitionKey switches the Handler to partitioned mode: a single
+// receiver routes each message to a worker chosen by fn, so messages sharing
+// a key are handled one at a time in the order they were received while
+// different keys are handled in parallel. Messages with an empty key are
+// spread round-robin.
+func WithPartitionKey(fn PartitionKeyFunc) Option {
+	return func(o *options) {
+		o.partitionKey = fn
+	}
+}
+
+// WithMaxInFlight bounds how many received messages may be waiting or in
+// progress at once in partitioned mode. Defaults to the worker count.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// pending is a dispatched message whose heartbeat runs from dispatch until
+// it is handled, covering the time spent queued behind its partition.
+type pending struct {
+	msg  broker.Message
+	stop func()
+}
+
+func (h *Handler[T]) startPartitioned(ctx context.Context, workerCount int) {
+	limit := h.options.maxInFlight
+	if limit < 1 {
+		limit = workerCount
+	}
+
+	inFlight := make(chan struct{}, limit)
+	partitions := make([]chan pending, workerCount)
+	for i := range partitions {
+		partitions[i] = make(chan pending, limit)
+	}
+
+	h.wg.Add(workerCount + 1)
+	for _, partition := range partitions {
+		go h.partitionWorker(ctx, partition, inFlight)
+	}
+	go h.dispatch(ctx, partitions, inFlight)
+}
+
+// dispatch receives messages and routes them to partitions until the handler
+// stops, then closes the partitions so workers finish what they were given.
+func (h *Handler[T]) dispatch(ctx context.Context, partitions []chan pending, inFlight chan struct{}) {
+	defer h.wg.Done()
+	defer func() {
+		for _, partition := range partitions {
+			close(partition)
+		}
+	}()
+
+	var next int
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ctx.Done():
+			return
+		case inFlight <- struct{}{}:
+		}
+
+		msg, ok := h.receive(ctx)
+		if !ok {
+			<-inFlight
+			return
+		}
+
+		index := next % len(partitions)
+		if key := h.options.partitionKey(msg); key != "" {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(key))
+			index = int(hash.Sum32() % uint32(len(partitions)))
+		} else {
+			next++
+		}
+
+		partitions[index] <- pending{msg: msg, stop: h.heartbeat(ctx, msg)}
+	}
+}
+
+func (h *Handler[T]) partitionWorker(ctx context.Context, messages <-chan pending, inFlight <-chan struct{}) {
+	defer h.wg.Done()
+
+	for p := range messages {
+		err := h.handle(ctx, p.msg)
+		p.stop()
+
+		h.settle(ctx, p.msg, err)
+		<-inFlight
+	}
+}
@@ -0,0 +1,144 @@
+package subscriber
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/plugin/broker/inmem"
+)
+
+func publishKeyed(t *testing.T, b *inmem.Broker, key, id string) {
+	t.Helper()
+	msg := inmem.NewMessage(order{ID: id}, "orders")
+	msg.Attributes().Add("X-Entity-ID", key)
+	if err := b.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+}
+
+func TestHandler_PartitionedPreservesOrderPerKey(t *testing.T) {
+	b := inmem.New()
+	const perKey = 20
+	keys := []string{"a", "b", "c"}
+
+	var (
+		mu     sync.Mutex
+		active = make(map[string]int)
+		seen   = make(map[string][]string)
+		done   = make(chan struct{}, len(keys)*perKey)
+	)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			key := o.ID[:1]
+
+			mu.Lock()
+			active[key]++
+			if active[key] > 1 {
+				t.Errorf("key %q handled concurrently", key)
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active[key]--
+			seen[key] = append(seen[key], o.ID)
+			mu.Unlock()
+
+			done <- struct{}{}
+			return nil
+		},
+		WithPartitionKey(PartitionByAttribute("X-Entity-ID")),
+	)
+
+	for i := range perKey {
+		for _, key := range keys {
+			publishKeyed(t, b, key, fmt.Sprintf("%s-%02d", key, i))
+		}
+	}
+	start(t, h, 4)
+
+	for range len(keys) * perKey {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, key := range keys {
+		for i, id := range seen[key] {
+			if want := fmt.Sprintf("%s-%02d", key, i); id != want {
+				t.Fatalf("key %q out of order: got %s at %d", key, id, i)
+			}
+		}
+	}
+}
+
+func TestHandler_PartitionedMaxInFlight(t *testing.T) {
+	b := inmem.New()
+	release := make(chan struct{})
+	started := make(chan string, 8)
+
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+	)
+	settled := make(chan struct{}, 8)
+
+	h := NewHandler(b.NewSubscriber("orders", inmem.WithDecoderTarget(order{})),
+		func(_ context.Context, o order) error {
+			mu.Lock()
+			inFlight++
+			peak = max(peak, inFlight)
+			mu.Unlock()
+
+			started <- o.ID
+			<-release
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return nil
+		},
+		WithPartitionKey(PartitionByAttribute("X-Entity-ID")),
+		WithMaxInFlight(2),
+		WithHooks(Hooks{OnSuccess: func(context.Context, broker.Message) { settled <- struct{}{} }}),
+	)
+
+	for i := range 4 {
+		publishKeyed(t, b, fmt.Sprintf("k-%d", i), fmt.Sprintf("o-%d", i))
+	}
+	start(t, h, 8)
+
+	await(t, started)
+	await(t, started)
+	select {
+	case id := <-started:
+		t.Fatalf("expected at most 2 messages in flight, %s started", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	for range 4 {
+		select {
+		case <-settled:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for messages to settle")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak > 2 {
+		t.Fatalf("expected peak in-flight of 2, got %d", peak)
+	}
+}
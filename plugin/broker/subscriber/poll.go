@@ -0,0 +1,82 @@
+package subscriber
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+// defaultSubscribeBackoff is the wait after consecutive Subscribe failures.
+var defaultSubscribeBackoff = ExponentialBackoff(100*time.Millisecond, 30*time.Second)
+
+// WithSubscribeBackoff sets the wait after a failed Subscribe or
+// SubscribeBatch, given the number of consecutive failures. Defaults to
+// ExponentialBackoff(100ms, 30s).
+func WithSubscribeBackoff(backoff func(failures int) time.Duration) Option {
+	return func(o *options) {
+		o.subscribeBackoff = backoff
+	}
+}
+
+// poll calls fetch until it returns without error, and reports false once ctx
+// is done or stop is closed. Errors are passed to Hooks.OnSubscribeError and
+// followed by a backoff; empty receives (broker.ErrNoMessages) are neither,
+// as the subscriber already waited for messages.
+func poll[M any](ctx context.Context, stop <-chan struct{}, o *options, fetch func(context.Context) (M, error)) (M, bool) {
+	var zero M
+
+	backoff := o.subscribeBackoff
+	if backoff == nil {
+		backoff = defaultSubscribeBackoff
+	}
+
+	for failures := 0; ; {
+		m, err := fetch(ctx)
+		switch {
+		case err == nil:
+			return m, true
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return zero, false
+		case errors.Is(err, broker.ErrNoMessages):
+			failures = 0
+		default:
+			failures++
+			if o.hooks.OnSubscribeError != nil {
+				o.hooks.OnSubscribeError(ctx, err)
+			}
+		}
+
+		if !wait(ctx, stop, failures, backoff) {
+			return zero, false
+		}
+	}
+}
+
+// wait sleeps for the backoff after failures, not at all when there are
+// none, and reports false once ctx is done or stop is closed.
+func wait(ctx context.Context, stop <-chan struct{}, failures int, backoff func(int) time.Duration) bool {
+	if failures == 0 {
+		select {
+		case <-stop:
+			return false
+		case <-ctx.Done():
+			return false
+		default:
+			return true
+		}
+	}
+
+	timer := time.NewTimer(backoff(failures))
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,74 @@
+package subscriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/broker"
+)
+
+func TestPoll_SkipsEmptyReceivesAndBacksOffOnErrors(t *testing.T) {
+	results := []error{
+		fmt.Errorf("queue: %w", broker.ErrNoMessages),
+		fmt.Errorf("queue: %w", broker.ErrNoMessages),
+		errors.New("throttled"),
+		errors.New("throttled"),
+		nil,
+	}
+
+	var reported []error
+	var waits []int
+	o := &options{
+		hooks: Hooks{OnSubscribeError: func(_ context.Context, err error) {
+			reported = append(reported, err)
+		}},
+		subscribeBackoff: func(failures int) time.Duration {
+			waits = append(waits, failures)
+			return time.Millisecond
+		},
+	}
+
+	got, ok := poll(context.Background(), make(chan struct{}), o, func(context.Context) (string, error) {
+		err := results[0]
+		results = results[1:]
+		if err != nil {
+			return "", err
+		}
+		return "msg", nil
+	})
+	if !ok || got != "msg" {
+		t.Fatalf("expected the message, got %q, %v", got, ok)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("expected only real errors to be reported, got %v", reported)
+	}
+	if len(waits) != 2 || waits[0] != 1 || waits[1] != 2 {
+		t.Fatalf("expected a growing backoff after each error, got %v", waits)
+	}
+}
+
+func TestPoll_StopsDuringBackoff(t *testing.T) {
+	stop := make(chan struct{})
+	o := &options{subscribeBackoff: func(int) time.Duration { return time.Hour }}
+
+	done := make(chan bool, 1)
+	go func() {
+		_, ok := poll(context.Background(), stop, o, func(context.Context) (string, error) {
+			return "", errors.New("unavailable")
+		})
+		done <- ok
+	}()
+	close(stop)
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("expected poll to report the stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("poll kept backing off after stop")
+	}
+}
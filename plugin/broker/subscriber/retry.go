@@ -0,0 +1,224 @@
+package subscriber
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/broker"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// Attribute keys read and written by the retry handling. ReceiveCountAttribute
+// and DelaySecondAttribute match the keys used by the sqs, inmem and
+// natsjetstream plugins.
+const (
+	RetryAttemptAttribute = "X-Retry-Attempt"
+	ReceiveCountAttribute = "X-Message-Receive-Count"
+	DelaySecondAttribute  = "X-Message-Delay-Second"
+)
+
+// maxDelaySeconds caps DelaySecondAttribute at the SQS limit of 15 minutes.
+const maxDelaySeconds = 900
+
+// RetryFactory builds the message re-published for another attempt, usually
+// with the transport's NewMessage so it is routed back to the same queue.
+type RetryFactory func(msg broker.Message) broker.Message
+
+// RetryPolicy controls recoverable and unclassified failures.
+type RetryPolicy struct {
+	// MaxAttempts is the number of deliveries before a message is dead-lettered.
+	// Zero means unlimited. Deliveries are counted by RetryAttemptAttribute
+	// when Publisher and Factory are set, otherwise by the broker's receive
+	// count (sqs, natsjetstream, inmem). Transports that report neither, such
+	// as core NATS, count every delivery as the first, so the limit is never
+	// reached there without Publisher and Factory.
+	MaxAttempts int
+	// Backoff returns the delay before the given retry, starting at 1. A
+	// re-published message is delayed by at most 15 minutes.
+	Backoff func(retry int) time.Duration
+	// Publisher and Factory, when both set, re-publish failed messages with
+	// RetryAttemptAttribute and DelaySecondAttribute set and commit the
+	// original. Otherwise the message is left uncommitted for the broker to
+	// redeliver.
+	Publisher broker.Publisher
+	Factory   RetryFactory
+}
+
+// ExponentialBackoff doubles initial on every retry, capped at maxDelay.
+func ExponentialBackoff(initial, maxDelay time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		delay := initial
+		for i := 1; i < retry && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		return min(delay, maxDelay)
+	}
+}
+
+// DeadLetter is the envelope published for messages that cannot be processed.
+type DeadLetter struct {
+	Reason      string              `json:"reason"`
+	FailureMode common.FailureMode  `json:"failure_mode"`
+	Attempts    int                 `json:"attempts"`
+	FailedAt    time.Time           `json:"failed_at"`
+	Payload     any                 `json:"payload"`
+	Attributes  map[string][]string `json:"attributes"`
+}
+
+// DeadLetterFactory wraps a DeadLetter into a message for the DLQ publisher.
+type DeadLetterFactory func(DeadLetter) broker.Message
+
+// Hooks are called after each message is settled, e.g. to record metrics.
+// Any of them may be nil.
+type Hooks struct {
+	OnSuccess        func(ctx context.Context, msg broker.Message)
+	OnRetry          func(ctx context.Context, msg broker.Message, err error, attempt int)
+	OnDeadLetter     func(ctx context.Context, msg broker.Message, err error)
+	OnDrop           func(ctx context.Context, msg broker.Message, err error)
+	OnSubscribeError func(ctx context.Context, err error)
+}
+
+// WithRetry configures how recoverable and unclassified failures are retried.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+// WithDeadLetter publishes non-recoverable failures, unexpected schemas and
+// messages out of retries to pub and then commits them. Without it such
+// messages are left uncommitted, leaving them to the broker's redrive policy.
+func WithDeadLetter(pub broker.Publisher, factory DeadLetterFactory) Option {
+	return func(o *options) {
+		o.deadLetter = pub
+		o.deadLetterFactory = factory
+	}
+}
+
+// WithHooks sets the settlement hooks.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// settler settles processed messages; it is shared by Handler and BatchHandler.
+type settler struct {
+	subscribe broker.Subscriber
+	options   *options
+}
+
+// settle commits, retries, dead-letters or drops msg according to
+// common.ClassifyFailureMode(err). Unclassified failures are retried like
+// recoverable ones and dead-lettered once out of retries; ErrUnexpectedSchema
+// never decodes into the hook's type, so it is dead-lettered straight away.
+func (s *settler) settle(ctx context.Context, msg broker.Message, err error) {
+	if err == nil {
+		if s.commit(ctx, msg) && s.options.hooks.OnSuccess != nil {
+			s.options.hooks.OnSuccess(ctx, msg)
+		}
+		return
+	}
+
+	mode := common.ClassifyFailureMode(err)
+	if errors.Is(err, ErrUnexpectedSchema) {
+		mode = common.FailureModeNonRecoverable
+	}
+
+	switch mode {
+	case common.FailureModeDrop:
+		if s.commit(ctx, msg) && s.options.hooks.OnDrop != nil {
+			s.options.hooks.OnDrop(ctx, msg, err)
+		}
+	case common.FailureModeRecoverable, common.FailureModeUnknown:
+		attempt := attemptOf(msg)
+		if limit := s.options.retry.MaxAttempts; limit > 0 && attempt >= limit {
+			s.deadLetter(ctx, msg, err, mode, attempt)
+			return
+		}
+		s.retry(ctx, msg, err, attempt)
+	default:
+		s.deadLetter(ctx, msg, err, mode, attemptOf(msg))
+	}
+}
+
+func (s *settler) retry(ctx context.Context, msg broker.Message, err error, attempt int) {
+	policy := s.options.retry
+
+	if policy.Publisher != nil && policy.Factory != nil {
+		next := policy.Factory(msg)
+		next.Attributes().Delete(RetryAttemptAttribute)
+		next.Attributes().Add(RetryAttemptAttribute, strconv.Itoa(attempt))
+		if policy.Backoff != nil {
+			seconds := min(int(policy.Backoff(attempt).Round(time.Second)/time.Second), maxDelaySeconds)
+			next.Attributes().Delete(DelaySecondAttribute)
+			next.Attributes().Add(DelaySecondAttribute, strconv.Itoa(seconds))
+		}
+
+		if pubErr := policy.Publisher.Publish(ctx, next); pubErr != nil {
+			logger.Of(ctx).ErrorS("Subscriber::Handler::Retry", logger.WithValue("error", pubErr))
+			return
+		}
+		if !s.commit(ctx, msg) {
+			return
+		}
+	}
+
+	if s.options.hooks.OnRetry != nil {
+		s.options.hooks.OnRetry(ctx, msg, err, attempt)
+	}
+}
+
+func (s *settler) deadLetter(ctx context.Context, msg broker.Message, err error, mode common.FailureMode, attempts int) {
+	if s.options.deadLetter == nil || s.options.deadLetterFactory == nil {
+		logger.Of(ctx).WarnS("Subscriber::Handler::DeadLetter",
+			logger.WithValue("failure_mode", string(mode)), logger.WithValue("error", err))
+		return
+	}
+
+	envelope := DeadLetter{
+		Reason:      err.Error(),
+		FailureMode: mode,
+		Attempts:    attempts,
+		FailedAt:    time.Now().UTC(),
+		Payload:     msg.Payload(),
+		Attributes:  msg.Attributes().Values(),
+	}
+	if pubErr := s.options.deadLetter.Publish(ctx, s.options.deadLetterFactory(envelope)); pubErr != nil {
+		logger.Of(ctx).ErrorS("Subscriber::Handler::DeadLetter", logger.WithValue("error", pubErr))
+		return
+	}
+
+	if s.commit(ctx, msg) && s.options.hooks.OnDeadLetter != nil {
+		s.options.hooks.OnDeadLetter(ctx, msg, err)
+	}
+}
+
+func (s *settler) commit(ctx context.Context, msg broker.Message) bool {
+	if err := s.subscribe.Commit(ctx, msg); err != nil {
+		logger.Of(ctx).ErrorS("Subscriber::Handler::Commit", logger.WithValue("error", err))
+		return false
+	}
+	return true
+}
+
+// attemptOf returns which delivery of msg this is, starting at 1: one more
+// than RetryAttemptAttribute for re-published messages, otherwise the
+// broker's receive count when it reports one. It is 1 on transports without
+// a receive count unless messages are re-published with RetryAttemptAttribute.
+func attemptOf(msg broker.Message) int {
+	if value, ok := msg.Attributes().Lookup(RetryAttemptAttribute); ok {
+		if retries, err := strconv.Atoi(value); err == nil {
+			return retries + 1
+		}
+	}
+	if value, ok := msg.Attributes().Lookup(ReceiveCountAttribute); ok {
+		if count, err := strconv.Atoi(value); err == nil && count > 0 {
+			return count
+		}
+	}
+	return 1
+}
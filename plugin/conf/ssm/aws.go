@@ -5,15 +5,43 @@ import (
 	"context"
 	"fmt"
 
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aawadallak/go-core-kit/plugin/awsconfig"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 )
 
-// newSSMClient creates a new AWS SSM client using default AWS configuration.
-// It loads the AWS configuration from the environment and returns a configured SSM client.
+type options struct {
+	client     *ssm.Client
+	awsOptions []awsconfig.Option
+}
+
+// Option configures the provider created by NewProvider.
+type Option func(*options)
+
+// WithClient uses client as is. It takes precedence over WithAwsOptions,
+// whose options are then ignored.
+func WithClient(client *ssm.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithAwsOptions overrides the default AWS configuration chain used to build
+// the client, e.g. with awsconfig.WithEndpoint for LocalStack.
+func WithAwsOptions(opts ...awsconfig.Option) Option {
+	return func(o *options) {
+		o.awsOptions = append(o.awsOptions, opts...)
+	}
+}
+
+// newSSMClient creates a new AWS SSM client from the options.
+// Unless a client is given, it loads the AWS configuration from the environment.
 // Returns an error if the AWS configuration cannot be loaded.
-func newSSMClient(ctx context.Context) (*ssm.Client, error) {
-	cfg, err := awsConfig.LoadDefaultConfig(ctx)
+func newSSMClient(ctx context.Context, o *options) (*ssm.Client, error) {
+	if o.client != nil {
+		return o.client, nil
+	}
+
+	cfg, err := awsconfig.Load(ctx, o.awsOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
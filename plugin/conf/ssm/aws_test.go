@@ -0,0 +1,34 @@
+package ssm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aawadallak/go-core-kit/plugin/awsconfig"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+func TestNewSSMClient_Options(t *testing.T) {
+	ctx := context.Background()
+	base := awsconfig.WithConfig(aws.Config{Region: "us-east-1"})
+
+	client, err := newSSMClient(ctx, &options{awsOptions: []awsconfig.Option{
+		base, awsconfig.WithEndpoint("http://localhost:4566"),
+	}})
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	if got := aws.ToString(client.Options().BaseEndpoint); got != "http://localhost:4566" {
+		t.Fatalf("unexpected endpoint %q", got)
+	}
+
+	given := ssm.New(ssm.Options{Region: "us-east-1"})
+	o := &options{}
+	for _, opt := range []Option{WithClient(given), WithAwsOptions(base, awsconfig.WithEndpoint("http://ignored"))} {
+		opt(o)
+	}
+	if client, err := newSSMClient(ctx, o); err != nil || client != given {
+		t.Fatalf("expected WithClient to take precedence, got %v", err)
+	}
+}
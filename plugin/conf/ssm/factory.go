@@ -23,8 +23,13 @@ var _ conf.Provider = (*provider)(nil)
 // NewProvider creates a new SSM configuration provider.
 // It initializes an AWS SSM client and returns a provider that can fetch parameters from AWS SSM.
 // If the SSM client initialization fails, it returns a no-op provider that does nothing.
-func NewProvider() conf.Provider {
-	client, err := newSSMClient(context.TODO())
+func NewProvider(opts ...Option) conf.Provider {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	client, err := newSSMClient(context.TODO(), o)
 	if err != nil {
 		return &noopProvider{}
 	}
@@ -75,8 +75,16 @@ func (p *JobRepository) UpdateJob(ctx context.Context, job *j.Job) error {
 		}).Error
 }
 
+// WithTransaction runs fn in a transaction. Audit entries recorded by
+// abstractrepo repositories inside fn are dispatched only once it commits.
 func (p *JobRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	return p.delegate.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	ctx, commit := abstractrepo.WithAfterCommit(ctx)
+	if err := p.delegate.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		return fn(abstractrepo.WithTx(ctx, tx))
-	})
+	}); err != nil {
+		return err
+	}
+
+	commit()
+	return nil
 }
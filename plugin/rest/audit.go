@@ -0,0 +1,364 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+// RequestExtractor derives a value from a request after the handler has run.
+type RequestExtractor func(r *http.Request) string
+
+// AuditOption configures the middleware returned by NewAuditMiddleware.
+type AuditOption func(*auditConfig)
+
+type auditConfig struct {
+	route           RequestExtractor
+	userID          RequestExtractor
+	trustedProxies  []netip.Prefix
+	queueSize       int
+	dispatchTimeout time.Duration
+	onDrop          func(audit.Log)
+}
+
+// Defaults for the bounded hand-off between requests and the audit.Service.
+const (
+	defaultAuditQueueSize       = 1024
+	defaultAuditDispatchTimeout = time.Second
+)
+
+// WithAuditRouteExtractor overrides how the route pattern is resolved. The
+// default uses the pattern matched by http.ServeMux and falls back to the path.
+func WithAuditRouteExtractor(fn RequestExtractor) AuditOption {
+	return func(c *auditConfig) {
+		c.route = fn
+	}
+}
+
+// WithAuditUserIDExtractor sets how the authenticated user is resolved.
+func WithAuditUserIDExtractor(fn RequestExtractor) AuditOption {
+	return func(c *auditConfig) {
+		c.userID = fn
+	}
+}
+
+// WithTrustedProxies enables X-Forwarded-For handling for requests arriving
+// from the given networks. Without it the client IP is always RemoteAddr.
+func WithTrustedProxies(prefixes ...netip.Prefix) AuditOption {
+	return func(c *auditConfig) {
+		c.trustedProxies = append(c.trustedProxies, prefixes...)
+	}
+}
+
+// WithAuditQueueSize bounds how many entries may wait for the audit.Service.
+// Entries arriving while the queue is full are dropped. Defaults to 1024;
+// sizes below 1 are ignored.
+func WithAuditQueueSize(size int) AuditOption {
+	return func(c *auditConfig) {
+		if size > 0 {
+			c.queueSize = size
+		}
+	}
+}
+
+// WithAuditDispatchTimeout bounds each Dispatch call; entries that time out
+// are dropped. Defaults to 1 second.
+func WithAuditDispatchTimeout(timeout time.Duration) AuditOption {
+	return func(c *auditConfig) {
+		c.dispatchTimeout = timeout
+	}
+}
+
+// WithAuditOnDrop is called for every entry dropped because the queue was
+// full or Dispatch failed, e.g. to count drops in a metric.
+func WithAuditOnDrop(fn func(audit.Log)) AuditOption {
+	return func(c *auditConfig) {
+		c.onDrop = fn
+	}
+}
+
+// AuditMiddleware records one audit.Log per request. Entries go through a
+// bounded queue to a background worker, so a slow audit.Service never delays
+// the response; Close drains the queue.
+type AuditMiddleware struct {
+	cfg   *auditConfig
+	svc   audit.Service
+	queue chan queuedLog
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAuditMiddleware records one audit.Log per request using audit.NewHTTPLog,
+// adding the latency in milliseconds to its metadata; see WithAuditQueueSize
+// and WithAuditDispatchTimeout. A panicking handler is recorded with status
+// 500 before the panic continues. Close it once the server has stopped
+// serving requests.
+//
+// The trace ID is read from common.RequestContext, so the middleware must run
+// after the one that stores it in the request context.
+func NewAuditMiddleware(svc audit.Service, opts ...AuditOption) *AuditMiddleware {
+	cfg := &auditConfig{
+		route:           defaultRoute,
+		userID:          func(*http.Request) string { return "" },
+		queueSize:       defaultAuditQueueSize,
+		dispatchTimeout: defaultAuditDispatchTimeout,
+		onDrop:          func(audit.Log) {},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	m := &AuditMiddleware{
+		cfg:   cfg,
+		svc:   svc,
+		queue: make(chan queuedLog, cfg.queueSize),
+		done:  make(chan struct{}),
+	}
+	go m.dispatch()
+
+	return m
+}
+
+// Handler implements Middleware.
+func (m *AuditMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			if p := recover(); p != nil {
+				recorder.status = http.StatusInternalServerError
+				m.enqueue(m.cfg.entry(r, recorder, start))
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		m.enqueue(m.cfg.entry(r, recorder, start))
+	})
+}
+
+// Close stops accepting entries, dropping later ones, and waits until the
+// queued entries are dispatched or ctx is done.
+func (m *AuditMiddleware) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// queuedLog is an entry waiting for the dispatch worker, with the request
+// context it is dispatched under.
+type queuedLog struct {
+	ctx   context.Context
+	entry audit.Log
+}
+
+func (c *auditConfig) entry(r *http.Request, recorder *statusRecorder, start time.Time) queuedLog {
+	var traceID string
+	if rc := common.RequestContextFrom(r.Context()); rc != nil {
+		traceID = rc.TraceID
+	}
+
+	entry := audit.NewHTTPLog(
+		traceID,
+		c.userID(r),
+		r.Method,
+		c.route(r),
+		recorder.statusCode(),
+		c.clientIP(r),
+	)
+	entry.Timestamp = start
+	entry.Metadata["latency_ms"] = time.Since(start).Milliseconds()
+
+	return queuedLog{ctx: context.WithoutCancel(r.Context()), entry: entry}
+}
+
+// enqueue hands the entry to the worker without blocking, dropping it when
+// the queue is full or the middleware is closed.
+func (m *AuditMiddleware) enqueue(item queuedLog) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.closed {
+		select {
+		case m.queue <- item:
+			return
+		default:
+		}
+	}
+
+	logger.Of(item.ctx).WarnS("Rest::AuditMiddleware::Dispatch",
+		logger.WithValue("message", "audit queue full or closed, dropping entry"),
+		logger.WithValue("action", item.entry.Action))
+	m.cfg.onDrop(item.entry)
+}
+
+// dispatch is the single worker draining the queue into the service until
+// Close.
+func (m *AuditMiddleware) dispatch() {
+	defer close(m.done)
+
+	for item := range m.queue {
+		ctx, cancel := context.WithTimeout(item.ctx, m.cfg.dispatchTimeout)
+		err := m.svc.Dispatch(ctx, item.entry)
+		cancel()
+
+		if err != nil {
+			logger.Of(item.ctx).ErrorS("Rest::AuditMiddleware::Dispatch",
+				logger.WithValue("error", err))
+			m.cfg.onDrop(item.entry)
+		}
+	}
+}
+
+func defaultRoute(r *http.Request) string {
+	if r.Pattern == "" {
+		return r.URL.Path
+	}
+	// ServeMux patterns may start with a method, which NewHTTPLog already records.
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return r.Pattern
+}
+
+// clientIP walks X-Forwarded-For from right to left, skipping trusted proxies,
+// and returns the first untrusted hop. Entries further left are client
+// controlled and are never trusted.
+func (c *auditConfig) clientIP(r *http.Request) string {
+	remote := remoteIP(r.RemoteAddr)
+	if !c.trusted(remote) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		client = hop
+		if !c.trusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func (c *auditConfig) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// statusRecorder captures the status code written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		if s.status == 0 {
+			s.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g. for websockets, which are recorded
+// as 101 Switching Protocols.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: hijack", http.ErrNotSupported)
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil && s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (s *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := s.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (s *statusRecorder) ReadFrom(r io.Reader) (int64, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return io.Copy(s.ResponseWriter, r)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) statusCode() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
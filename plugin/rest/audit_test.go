@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/logger"
+)
+
+func TestMain(m *testing.M) {
+	// Init global logger before tests to avoid data race in the dispatch worker.
+	logger.SetInstance(logger.New())
+	m.Run()
+}
+
+type chanService struct {
+	logs chan audit.Log
+}
+
+func (c *chanService) Dispatch(_ context.Context, log audit.Log) error {
+	c.logs <- log
+	return nil
+}
+
+func (c *chanService) Flush(_ context.Context) error { return nil }
+
+func (c *chanService) Close(_ context.Context) error { return nil }
+
+func (c *chanService) next(t *testing.T) audit.Log {
+	t.Helper()
+	select {
+	case log := <-c.logs:
+		return log
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for audit log")
+		return audit.Log{}
+	}
+}
+
+func TestAuditMiddleware_RecordsRequest(t *testing.T) {
+	svc := &chanService{logs: make(chan audit.Log, 1)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /items/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		_ = NewStatusCreated(w)
+	})
+
+	withRequestContext := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := common.WithRequestContext(r.Context(), &common.RequestContext{TraceID: "trace-1"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+
+	mw := NewAuditMiddleware(svc, WithAuditUserIDExtractor(func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	}))
+	handler := withRequestContext(mw.Handler(mux))
+
+	req := httptest.NewRequest(http.MethodPost, "/items/42", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("X-User", "user-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	log := svc.next(t)
+	if log.Action != "POST /items/{id}" {
+		t.Errorf("unexpected action %q", log.Action)
+	}
+	if log.TraceID != "trace-1" {
+		t.Errorf("expected trace ID %q, got %q", "trace-1", log.TraceID)
+	}
+	if log.UserID != "user-1" {
+		t.Errorf("expected user ID %q, got %q", "user-1", log.UserID)
+	}
+	if got := log.Metadata["status_code"]; got != http.StatusCreated {
+		t.Errorf("expected status %d, got %v", http.StatusCreated, got)
+	}
+	if got := log.Metadata["ip"]; got != "203.0.113.7" {
+		t.Errorf("expected ip %q, got %v", "203.0.113.7", got)
+	}
+	if _, ok := log.Metadata["latency_ms"]; !ok {
+		t.Error("expected latency_ms in metadata")
+	}
+}
+
+func TestAuditMiddleware_ClientIP(t *testing.T) {
+	cfg := &auditConfig{trustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		want   string
+	}{
+		{name: "untrusted remote ignores header", remote: "203.0.113.7:1", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted remote uses header", remote: "10.0.0.1:1", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "skips trusted hops", remote: "10.0.0.1:1", xff: "198.51.100.1, 10.0.0.2", want: "198.51.100.1"},
+		{name: "stops at first untrusted hop", remote: "10.0.0.1:1", xff: "1.1.1.1, 198.51.100.1, 10.0.0.2", want: "198.51.100.1"},
+		{name: "all trusted uses leftmost", remote: "10.0.0.1:1", xff: "10.0.0.3, 10.0.0.2", want: "10.0.0.3"},
+		{name: "no header", remote: "10.0.0.1:1", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if got := cfg.clientIP(req); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuditMiddleware_RecordsPanics(t *testing.T) {
+	svc := &chanService{logs: make(chan audit.Log, 1)}
+	handler := NewAuditMiddleware(svc).Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("expected the panic to be re-raised, got %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	}()
+
+	if got := svc.next(t).Metadata["status_code"]; got != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %v", http.StatusInternalServerError, got)
+	}
+}
+
+// stalledService blocks every Dispatch until its context is done.
+type stalledService struct{ chanService }
+
+func (stalledService) Dispatch(ctx context.Context, _ audit.Log) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestAuditMiddleware_DropsUnderBackpressure(t *testing.T) {
+	var dropped atomic.Int32
+	mw := NewAuditMiddleware(&stalledService{},
+		WithAuditQueueSize(1),
+		WithAuditDispatchTimeout(50*time.Millisecond),
+		WithAuditOnDrop(func(audit.Log) { dropped.Add(1) }),
+	)
+	handler := mw.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	start := time.Now()
+	for range 5 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("requests waited on the audit service for %v", elapsed)
+	}
+
+	// At most one entry is in the worker and one in the queue; the rest are
+	// dropped at once, and the others once their dispatch times out.
+	if got := dropped.Load(); got < 3 {
+		t.Fatalf("expected full-queue drops, got %d", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for dropped.Load() != 5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := dropped.Load(); got != 5 {
+		t.Fatalf("expected timed out entries to be dropped too, got %d", got)
+	}
+}
+
+func TestAuditMiddleware_CloseDrainsQueue(t *testing.T) {
+	svc := &chanService{logs: make(chan audit.Log, 3)}
+	var dropped atomic.Int32
+	mw := NewAuditMiddleware(svc,
+		WithAuditQueueSize(0),
+		WithAuditOnDrop(func(audit.Log) { dropped.Add(1) }),
+	)
+	handler := mw.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for range 3 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := mw.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := len(svc.logs); got != 3 {
+		t.Fatalf("expected 3 dispatched entries, got %d", got)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+	if got := dropped.Load(); got != 1 {
+		t.Fatalf("expected the entry after Close to be dropped, got %d drops", got)
+	}
+	if err := mw.Close(ctx); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestAuditMiddleware_PassesThroughHijack(t *testing.T) {
+	svc := &chanService{logs: make(chan audit.Log, 2)}
+	var hijackErr error
+	handler := NewAuditMiddleware(svc).Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _, hijackErr = http.NewResponseController(w).Hijack()
+	}))
+
+	w := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	if hijackErr != nil || !w.hijacked {
+		t.Fatalf("expected the connection to be hijacked, got %v", hijackErr)
+	}
+	if got := svc.next(t).Metadata["status_code"]; got != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %v", http.StatusSwitchingProtocols, got)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if !errors.Is(hijackErr, http.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", hijackErr)
+	}
+	if got := svc.next(t).Metadata["status_code"]; got != http.StatusOK {
+		t.Fatalf("expected status %d without hijack support, got %v", http.StatusOK, got)
+	}
+}
@@ -0,0 +1,32 @@
+package restchi
+
+import (
+	"net/http"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/plugin/rest"
+	"github.com/go-chi/chi/v5"
+)
+
+// RoutePattern returns the chi route pattern matched for the request, such as
+// "/users/{id}", falling back to the request path outside a chi router.
+func RoutePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return r.URL.Path
+}
+
+// WithAudit adds a global rest.NewAuditMiddleware that resolves routes with
+// RoutePattern. Options are applied after the chi route extractor. The
+// middleware is closed, draining its queue, by Server.Shutdown.
+func WithAudit(svc audit.Service, opts ...rest.AuditOption) Option {
+	opts = append([]rest.AuditOption{rest.WithAuditRouteExtractor(RoutePattern)}, opts...)
+	return func(s *server) {
+		m := rest.NewAuditMiddleware(svc, opts...)
+		s.middlewares = append(s.middlewares, m.Handler)
+		s.closers = append(s.closers, m.Close)
+	}
+}
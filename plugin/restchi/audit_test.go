@@ -0,0 +1,55 @@
+package restchi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/plugin/rest"
+)
+
+type chanService struct {
+	logs chan audit.Log
+}
+
+func (c *chanService) Dispatch(_ context.Context, log audit.Log) error {
+	c.logs <- log
+	return nil
+}
+
+func (c *chanService) Flush(_ context.Context) error { return nil }
+
+func (c *chanService) Close(_ context.Context) error { return nil }
+
+func TestWithAudit_UsesChiRoutePattern(t *testing.T) {
+	svc := &chanService{logs: make(chan audit.Log, 1)}
+
+	srv := NewServer(
+		WithPrefix("/api"),
+		WithAudit(svc),
+		WithRoute(rest.Router{
+			Method:  http.MethodGet,
+			Pattern: "/users/{id}",
+			Handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+		}),
+	)
+
+	srv.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/42", nil))
+
+	select {
+	case log := <-svc.logs:
+		if log.Action != "GET /api/users/{id}" {
+			t.Errorf("expected route pattern action, got %q", log.Action)
+		}
+		if got := log.Metadata["status_code"]; got != http.StatusNoContent {
+			t.Errorf("expected status %d, got %v", http.StatusNoContent, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for audit log")
+	}
+}
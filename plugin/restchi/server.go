@@ -28,6 +28,7 @@ type server struct {
 	mux         *chi.Mux
 	httpServer  *http.Server // Store the http.Server instance
 	middlewares []rest.Middleware
+	closers     []func(context.Context) error
 	routes      rest.Routes
 	prefix      string
 	addr        string
@@ -97,7 +98,8 @@ func (s *server) Start(ctx context.Context) error {
 	}
 }
 
-// Shutdown gracefully shuts down the HTTP server.
+// Shutdown gracefully shuts down the HTTP server, then closes what the
+// options registered to be closed with it, such as WithAudit's middleware.
 func (s *server) Shutdown(_ context.Context) error {
 	// Create a shutdown context with a timeout.
 	// This context is separate from the one passed to Start.
@@ -108,6 +110,12 @@ func (s *server) Shutdown(_ context.Context) error {
 		return err // Return the error if shutdown fails
 	}
 
+	for _, closer := range s.closers {
+		if err := closer(shutdownCtx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -97,6 +97,10 @@ func WithRetryPredicate(v RetryPredicate) Option {
 	}
 }
 
+// WithinTransaction runs fn in a transaction, retrying errors accepted by the
+// RetryPredicate. Audit entries recorded by abstractrepo repositories inside
+// fn are dispatched only once the transaction commits, so a rolled back or
+// retried attempt is never audited.
 func (m *Manager) WithinTransaction(ctx context.Context, fn txm.Fn) error {
 	if fn == nil {
 		return errors.New("txmgorm: fn is nil")
@@ -108,9 +112,13 @@ func (m *Manager) WithinTransaction(ctx context.Context, fn txm.Fn) error {
 			return fn(ctx)
 		}
 
+		txCtx, commit := abstractrepo.WithAfterCommit(ctx)
 		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			return fn(abstractrepo.WithTx(ctx, tx))
+			return fn(abstractrepo.WithTx(txCtx, tx))
 		})
+		if err == nil {
+			commit()
+		}
 
 		if attempt >= m.maxRetries || !m.shouldRetry(err) {
 			return err
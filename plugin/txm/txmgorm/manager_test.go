@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aawadallak/go-core-kit/common"
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/plugin/abstractrepo"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -97,3 +100,59 @@ func TestWithinTransaction_StopsOnContextCancelDuringBackoff(t *testing.T) {
 		t.Fatalf("expected 1 attempt before cancel, got %d", attempts)
 	}
 }
+
+type auditedRow struct {
+	common.Entity
+	Name string `json:"name"`
+}
+
+type recordingService struct {
+	logs []audit.Log
+}
+
+func (s *recordingService) Dispatch(_ context.Context, log audit.Log) error {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *recordingService) Flush(_ context.Context) error { return nil }
+
+func (s *recordingService) Close(_ context.Context) error { return nil }
+
+func TestWithinTransaction_AuditsOnlyCommittedAttempt(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&auditedRow{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	svc := &recordingService{}
+	repo, err := abstractrepo.NewAbstractRepository[auditedRow](db, abstractrepo.WithAudit(svc, ""))
+	if err != nil {
+		t.Fatalf("unexpected repository error: %v", err)
+	}
+	manager, err := New(db,
+		WithMaxRetries(1),
+		WithInitialBackoff(time.Millisecond),
+		WithMaxBackoff(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("unexpected new error: %v", err)
+	}
+
+	attempts := 0
+	err = manager.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if _, err := repo.Save(ctx, &auditedRow{Name: "row"}); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return errors.New("deadlock detected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected transaction error: %v", err)
+	}
+	if len(svc.logs) != 1 {
+		t.Fatalf("expected only the committed attempt to be audited, got %d logs", len(svc.logs))
+	}
+}
@@ -0,0 +1,85 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aawadallak/go-core-kit/core/audit"
+	"github.com/aawadallak/go-core-kit/core/repository"
+	"github.com/aawadallak/go-core-kit/plugin/audit/auditgorm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupPostgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("test"),
+		postgres.WithPassword("test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(30*time.Second),
+		),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, pgContainer.Terminate(ctx))
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	db, err := gorm.Open(gormpostgres.Open(connStr), &gorm.Config{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestAuditTableIsAppendOnly(t *testing.T) {
+	db := setupPostgres(t)
+	ctx := context.Background()
+
+	// A mixed-case name exercises identifier quoting.
+	provider, err := auditgorm.NewProvider(db, "Audit_Logs")
+	require.NoError(t, err)
+	require.NoError(t, provider.EnsureSchema(ctx))
+	// EnsureSchema is idempotent.
+	require.NoError(t, provider.EnsureSchema(ctx))
+
+	require.NoError(t, provider.Flush(ctx, audit.Log{
+		Timestamp: time.Now(),
+		UserID:    "alice",
+		Action:    "order.create",
+		Metadata:  map[string]any{"order_id": "order-123"},
+	}))
+
+	statements := map[string]string{
+		"update":   `UPDATE "Audit_Logs" SET user_id = 'mallory'`,
+		"delete":   `DELETE FROM "Audit_Logs"`,
+		"truncate": `TRUNCATE "Audit_Logs"`,
+	}
+	for name, stmt := range statements {
+		t.Run(name, func(t *testing.T) {
+			err := db.WithContext(ctx).Exec(stmt).Error
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "append-only")
+		})
+	}
+
+	page, err := provider.FindAll(ctx, &repository.PaginationQuery[auditgorm.Filter]{})
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "alice", page.Data[0].UserID)
+}